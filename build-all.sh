@@ -1,4 +1,4 @@
 #!/bin/bash
 
-GOOS=linux GOARCH=amd64 go build -o ./bin/linux_x64 ./cmd/main.go
-GOOS=linux GOARCH=386 go build -o ./bin/linux_x32 ./cmd/main.go
\ No newline at end of file
+GOOS=linux GOARCH=amd64 go build -o ./bin/linux_x64 ./cmd
+GOOS=linux GOARCH=386 go build -o ./bin/linux_x32 ./cmd
\ No newline at end of file
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"asterizm/builder/config"
+	"asterizm/builder/dockercompose"
+	"asterizm/builder/utils"
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// shorter values are not redacted, they would mangle the output
+const minSecretLen = 6
+
+// command is a shell command of the deploy
+// secrets are passed to the command stdin, so they are not visible in ps and printed commands
+type command struct {
+	line  string
+	stdin string
+}
+
+var (
+	redactedMu sync.Mutex
+	redacted   = map[string]struct{}{}
+	redactor   = strings.NewReplacer()
+)
+
+// addSecrets registers values which are replaced with *** in the builder output
+func addSecrets(values ...string) {
+	redactedMu.Lock()
+	defer redactedMu.Unlock()
+
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if len(value) >= minSecretLen {
+			redacted[value] = struct{}{}
+		}
+	}
+
+	// longer values first, so a secret containing another one is replaced entirely
+	values = utils.MapKeys(redacted)
+	sort.Slice(values, func(i, j int) bool {
+		if len(values[i]) != len(values[j]) {
+			return len(values[i]) > len(values[j])
+		}
+		return values[i] < values[j]
+	})
+
+	oldnew := make([]string, 0, len(values)*2)
+	for _, value := range values {
+		oldnew = append(oldnew, value, "***")
+	}
+	redactor = strings.NewReplacer(oldnew...)
+}
+
+// addConfigSecrets registers secrets of the config, including encrypted owner keys
+func addConfigSecrets(cfg *config.Config) {
+	addSecrets(cfg.Secrets()...)
+}
+
+// addOwnerSecrets registers encrypted and decrypted owner keys
+func addOwnerSecrets(encryptor *utils.Encryptor, owners map[string]config.Node) {
+	for network, owner := range owners {
+		if owner.OwnerPrivateKey == nil {
+			continue
+		}
+
+		addSecrets(*owner.OwnerPrivateKey)
+		if plaintext, err := encryptor.DecryptFor(network, []byte(*owner.OwnerPrivateKey)); err == nil {
+			addSecrets(string(plaintext))
+		}
+	}
+}
+
+// redact replaces registered secrets in the string
+func redact(str string) string {
+	redactedMu.Lock()
+	defer redactedMu.Unlock()
+
+	return redactor.Replace(str)
+}
+
+// shellQuote quotes the value for sh, plain words are kept as is
+func shellQuote(value string) string {
+	if value != "" && strings.Trim(value, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_.-") == "" {
+		return value
+	}
+
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// ownerCommands returns owners registration commands in the network order
+// the key argument is "-", the console reads the owner key from stdin, so it isn't a part of any command line
+func ownerCommands(encryptor *utils.Encryptor, owners map[string]config.Node) ([]command, error) {
+	nodeList, err := config.ConsoleOwners(encryptor, owners)
+	if err != nil {
+		return nil, err
+	}
+	addOwnerSecrets(encryptor, nodeList)
+
+	var commands []command
+
+	networks := utils.MapKeys(nodeList)
+	sort.Strings(networks)
+
+	for _, k := range networks {
+		v := nodeList[k]
+
+		line := fmt.Sprintf("docker exec -i %s ./main owners/add %s -", dockercompose.AsterizmConsole, shellQuote(k))
+		if v.OwnerPublicKey != nil {
+			line += " " + shellQuote(*v.OwnerPublicKey)
+		}
+
+		if v.OwnerWalletType != nil {
+			line += " " + shellQuote(*v.OwnerWalletType)
+		}
+
+		commands = append(commands, command{
+			line:  line,
+			stdin: *v.OwnerPrivateKey + "\n",
+		})
+	}
+
+	return commands, nil
+}
+
+// shellCommands returns commands without stdin
+func shellCommands(lines ...string) []command {
+	commands := make([]command, 0, len(lines))
+	for _, line := range lines {
+		commands = append(commands, command{line: line})
+	}
+
+	return commands
+}
+
+// runCommands runs the commands one by one
+// if a command fails, it and the following commands are printed to run them manually
+func runCommands(commands []command) error {
+	for i, c := range commands {
+		if err := processCommand(c); err != nil {
+			printCommandsError(commands[i:])
+			return err
+		}
+	}
+
+	return nil
+}
+
+func printCommandsError(commands []command) {
+	for _, c := range commands {
+		printCommandError(c)
+	}
+}
+
+func printCommandError(c command) {
+	if c.stdin != "" {
+		fmt.Printf("Please, run %q manually and enter the owner private key \n", redact(c.line))
+		return
+	}
+
+	fmt.Printf("Please, run %q manually \n", redact(c.line))
+}
+
+func printCommand(c command) {
+	if c.stdin != "" {
+		fmt.Printf("Run %q with the owner private key on stdin \n", redact(c.line))
+		return
+	}
+
+	fmt.Printf("Run %q \n", redact(c.line))
+}
+
+// processCommand runs the command line with bash -c
+func processCommand(c command) error {
+	cmd := exec.Command("bash", "-c", c.line)
+	cmd.Stdin = strings.NewReader(c.stdin)
+
+	return runRedacted(cmd)
+}
+
+// runRedacted runs the command and prints its output with redacted secrets
+func runRedacted(cmd *exec.Cmd) error {
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		fmt.Println(redact(err.Error()))
+		os.Exit(1)
+	}
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		fmt.Println(redact(err.Error()))
+		os.Exit(1)
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		fmt.Println(redact(err.Error()))
+		os.Exit(1)
+	}
+
+	// print the output of the subprocess, pipes must be read before Wait
+	var wg sync.WaitGroup
+	for _, pipe := range []io.Reader{stdout, stderr} {
+		wg.Add(1)
+		go func(pipe io.Reader) {
+			defer wg.Done()
+			scanner := bufio.NewScanner(pipe)
+			for scanner.Scan() {
+				fmt.Println(redact(scanner.Text()))
+			}
+		}(pipe)
+	}
+	wg.Wait()
+
+	return cmd.Wait()
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"asterizm/builder/config"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	addSecrets("redact-secret", "redact-secret-longer", "short", "  redact-padded  ")
+
+	for str, want := range map[string]string{
+		"key redact-secret is wrong":        "key *** is wrong",
+		"key redact-secret-longer is wrong": "key *** is wrong",
+		"padded redact-padded value":        "padded *** value",
+		// shorter values would mangle the output
+		"short values are kept": "short values are kept",
+	} {
+		if got := redact(str); got != want {
+			t.Errorf("redact(%q) = %q, want %q", str, got, want)
+		}
+	}
+}
+
+func TestOwnerCommands(t *testing.T) {
+	encryptor := (&config.Encryption{Key: "test-encryption-key", Salt: "test-encryption-salt", CipherMethod: config.DefaultCipherMethod}).Encryptor()
+
+	bound, err := encryptor.EncryptFor("ETH", []byte(testOwnerKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ethKey, publicKey, walletType := string(bound), "it's a public key", "v4r2"
+	_, err = ownerCommands(encryptor, map[string]config.Node{
+		"ETH": {OwnerPrivateKey: &ethKey},
+		"TON": {OwnerPrivateKey: &ethKey, OwnerPublicKey: &publicKey, OwnerWalletType: &walletType},
+	})
+
+	// the ETH value can't be decrypted for TON
+	if err == nil {
+		t.Fatal("value of another network is accepted")
+	}
+
+	commands, err := ownerCommands(encryptor, map[string]config.Node{"ETH": {OwnerPrivateKey: &ethKey, OwnerPublicKey: &publicKey}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(commands) != 1 {
+		t.Fatalf("commands %v", commands)
+	}
+
+	c := commands[0]
+	if want := `docker exec -i asterizm-cs-console ./main owners/add ETH - 'it'\''s a public key'`; c.line != want {
+		t.Errorf("line %q, want %q", c.line, want)
+	}
+
+	// the console gets the legacy value on stdin, neither it nor the plaintext key is a part of the line
+	value := strings.TrimSuffix(c.stdin, "\n")
+	if plaintext, err := encryptor.Decrypt([]byte(value), "", ""); err != nil || string(plaintext) != testOwnerKey {
+		t.Errorf("stdin %q decrypts to %q, %v", c.stdin, plaintext, err)
+	}
+
+	for _, secret := range []string{testOwnerKey, ethKey, value} {
+		if strings.Contains(c.line, secret) {
+			t.Errorf("line %q contains the owner key", c.line)
+		}
+	}
+
+	// the console value and the plaintext key are printed as *** from now on
+	for _, secret := range []string{testOwnerKey, value} {
+		if redact(secret) != "***" {
+			t.Errorf("owner key %q isn't redacted", secret)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"asterizm/builder/config"
+	"asterizm/builder/networks"
+	"asterizm/builder/utils"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+const (
+	// encryptionKeyEnv and encryptionSaltEnv override the config settings, secrets aren't passed as flags
+	encryptionKeyEnv  = "ASTERIZM_ENCRYPTION_KEY"
+	encryptionSaltEnv = "ASTERIZM_ENCRYPTION_SALT"
+)
+
+type cryptFunc func(encryptor *utils.Encryptor, network string, value []byte) ([]byte, error)
+
+func encryptCommand(args []string) error {
+	return cryptCommand("encrypt", args, "Value to encrypt: ", (*utils.Encryptor).EncryptFor)
+}
+
+func decryptCommand(args []string) error {
+	return cryptCommand("decrypt", args, "Value to decrypt: ", (*utils.Encryptor).DecryptFor)
+}
+
+// cryptCommand encrypts or decrypts a value from stdin with the config Utils.Encryption settings
+func cryptCommand(name string, args []string, prompt string, crypt cryptFunc) error {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	configPath := flags.String("f", "", "Config file path")
+	network := flags.String("network", "", "Network of the owner key, the value is bound to the network subkeys")
+	flags.Parse(args)
+
+	if *configPath == "" {
+		flags.Usage()
+		return errors.New("config path is required")
+	}
+
+	encryption, err := loadEncryption(*configPath)
+	if err != nil {
+		return err
+	}
+
+	// network codes of the config are canonical, a typo would bind the value to a network which doesn't exist
+	if *network != "" {
+		registered, ok := networks.Lookup(*network)
+		if !ok {
+			message := fmt.Sprintf("unknown network %s", *network)
+			if suggestion := networks.Suggest(*network); suggestion != "" {
+				message += fmt.Sprintf(", did you mean %q?", suggestion)
+			}
+			return fmt.Errorf("%s (supported networks: %s)", message, strings.Join(networks.Codes(), ", "))
+		}
+		*network = registered.Code
+	}
+
+	value, err := readSecret(prompt)
+	if err != nil {
+		return err
+	}
+
+	result, err := crypt(encryption.Encryptor(), *network, value)
+	if err != nil {
+		return fmt.Errorf("%s error: %w", name, err)
+	}
+
+	fmt.Println(string(result))
+	return nil
+}
+
+// loadEncryption returns the config encryption settings overridden by the environment
+func loadEncryption(configPath string) (*config.Encryption, error) {
+	encryption, err := config.LoadEncryption(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if key := os.Getenv(encryptionKeyEnv); key != "" {
+		encryption.Key = key
+	}
+
+	if salt := os.Getenv(encryptionSaltEnv); salt != "" {
+		encryption.Salt = salt
+	}
+
+	if encryption.Key == "" || encryption.Salt == "" {
+		return nil, fmt.Errorf("please, fill Utils.Encryption.Key and Utils.Encryption.Salt, set %s and %s or deploy once to generate them", encryptionKeyEnv, encryptionSaltEnv)
+	}
+
+	return encryption, nil
+}
+
+// readSecret reads a value from the terminal without echo or from piped stdin
+func readSecret(prompt string) ([]byte, error) {
+	var (
+		value []byte
+		err   error
+	)
+
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, prompt)
+		value, err = term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+	} else {
+		value, err = io.ReadAll(os.Stdin)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("read value: %w", err)
+	}
+
+	value = []byte(strings.TrimRight(string(value), "\r\n"))
+	if len(value) == 0 {
+		return nil, errors.New("empty value")
+	}
+
+	return value, nil
+}
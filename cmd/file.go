@@ -0,0 +1,97 @@
+package main
+
+import (
+	"asterizm/builder/utils"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+)
+
+func encryptFileCommand(args []string) error {
+	return cryptFileCommand("encrypt-file", args, func(encryptor *utils.Encryptor, dst io.Writer, src io.Reader) error {
+		writer, err := encryptor.EncryptWriter(dst)
+		if err != nil {
+			return err
+		}
+
+		if _, err := io.Copy(writer, src); err != nil {
+			return err
+		}
+
+		return writer.Close()
+	})
+}
+
+func decryptFileCommand(args []string) error {
+	return cryptFileCommand("decrypt-file", args, func(encryptor *utils.Encryptor, dst io.Writer, src io.Reader) error {
+		reader, err := encryptor.DecryptReader(src)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(dst, reader)
+		return err
+	})
+}
+
+// cryptFileCommand streams input file (or stdin) to output file (or stdout) through the config encryption
+func cryptFileCommand(name string, args []string, crypt func(encryptor *utils.Encryptor, dst io.Writer, src io.Reader) error) error {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	configPath := flags.String("f", "", "Config file path")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s [flags] <input> <output>, use - for stdin or stdout\n", name)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *configPath == "" || flags.NArg() != 2 {
+		flags.Usage()
+		return errors.New("config path, input and output are required")
+	}
+
+	encryption, err := loadEncryption(*configPath)
+	if err != nil {
+		return err
+	}
+
+	input, output := flags.Arg(0), flags.Arg(1)
+
+	src := io.Reader(os.Stdin)
+	if input != "-" {
+		file, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		src = file
+	}
+
+	if output == "-" {
+		if err := crypt(encryption.Encryptor(), os.Stdout, src); err != nil {
+			return fmt.Errorf("%s error: %w", name, err)
+		}
+
+		return nil
+	}
+
+	// the output appears only if the whole input is processed and authenticated
+	dst, err := os.CreateTemp(path.Dir(output), "."+path.Base(output)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(dst.Name())
+
+	if err := crypt(encryption.Encryptor(), dst, src); err != nil {
+		dst.Close()
+		return fmt.Errorf("%s error: %w", name, err)
+	}
+
+	if err := dst.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(dst.Name(), output)
+}
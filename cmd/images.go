@@ -0,0 +1,101 @@
+package main
+
+import (
+	"asterizm/builder/config"
+	"asterizm/builder/dockercompose"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// resolveDigest is replaced in tests, which don't run docker
+var resolveDigest = resolveImageDigest
+
+// pinImages pins floating image tags to digests, so restarts and other hosts run the same versions
+// digests of the previous run are reused unless update is set, the others are resolved if resolve is set
+func pinImages(cfg *config.Config, configPath string, resolve, update bool) error {
+	pinned, err := config.PinnedImages(configPath)
+	if err != nil {
+		return err
+	}
+
+	names := []string{config.ClientServerImage}
+	if cfg.Utils.Db.Host == dockercompose.DbHost {
+		names = append(names, config.PostgresImage)
+	}
+
+	for _, name := range names {
+		image := cfg.Image(name)
+		if !image.IsFloating() {
+			continue
+		}
+
+		previous, ok := pinned[name]
+		if ok && previous.Repository == image.Repository && previous.Tag == image.Tag && !update {
+			if err := cfg.PinImage(name, previous.Digest); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !resolve {
+			continue
+		}
+
+		digest, err := resolveDigest(image)
+		if err != nil {
+			return fmt.Errorf("resolve %s image: %w", image.Reference(), err)
+		}
+
+		if err := cfg.PinImage(name, digest); err != nil {
+			return err
+		}
+
+		fmt.Printf("Image %s is pinned to %s, set Deployment.Images.%s.Digest to run it on other hosts \n", image.Reference(), digest, name)
+	}
+
+	return nil
+}
+
+// resolveImageDigest pulls the image and returns its repository digest
+// a stale local image isn't pinned if the pull fails, the previous pin is reused without pulling unless update is set
+func resolveImageDigest(image config.Image) (string, error) {
+	reference := image.Reference()
+	if err := processCommand(command{line: "docker pull -q " + reference}); err != nil {
+		return "", fmt.Errorf("pull %s error: %w", reference, err)
+	}
+
+	output, err := exec.Command("docker", "image", "inspect", "--format", "{{json .RepoDigests}}", reference).Output()
+	if err != nil {
+		return "", fmt.Errorf("inspect image: %w", err)
+	}
+
+	var repoDigests []string
+	if err := json.Unmarshal(output, &repoDigests); err != nil {
+		return "", fmt.Errorf("inspect image: %w", err)
+	}
+
+	return repositoryDigest(image, repoDigests)
+}
+
+// repositoryDigest returns the digest of the image repository, digests of other repositories with the same image id are ignored
+func repositoryDigest(image config.Image, repoDigests []string) (string, error) {
+	for _, repoDigest := range repoDigests {
+		repository, digest, ok := strings.Cut(repoDigest, "@")
+		if ok && normalizeRepository(repository) == normalizeRepository(image.Repository) {
+			return digest, nil
+		}
+	}
+
+	return "", fmt.Errorf("image has no digest of %s repository, it isn't pulled from it", image.Repository)
+}
+
+// normalizeRepository drops the default registry and namespace, docker reports "postgres" for "docker.io/library/postgres"
+func normalizeRepository(repository string) string {
+	for _, registry := range []string{"docker.io/", "index.docker.io/", "registry-1.docker.io/"} {
+		repository = strings.TrimPrefix(repository, registry)
+	}
+
+	return strings.TrimPrefix(repository, "library/")
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"asterizm/builder/config"
+	"asterizm/builder/dockercompose"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var (
+	previousDigest = "sha256:" + strings.Repeat("0a", 32)
+	resolvedDigest = "sha256:" + strings.Repeat("1b", 32)
+)
+
+// imageConfig parses the source config with a docker db, the runtime config is written to the state dir if it isn't empty
+func imageConfig(t *testing.T, source, runtime string) (*config.Config, string) {
+	t.Helper()
+
+	configFile := filepath.Join(t.TempDir(), "config.yml")
+	source += `Nodes:
+  List:
+    ETH:
+      RPC: https://rpc.example.com
+      ContractAddress: "0x0000000000000000000000000000000000000001"
+`
+	if err := os.WriteFile(configFile, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if runtime != "" {
+		if err := os.MkdirAll(config.StateDir(configFile), 0700); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(config.StatePath(configFile, config.RuntimeConfigName), []byte(runtime), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg, err := config.ParseAndRefreshConfig(dockercompose.DbHost, configFile, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cfg, configFile
+}
+
+// stubResolveDigest replaces the docker pull with the result, resolved images are collected
+func stubResolveDigest(t *testing.T, digest string, err error) *[]string {
+	t.Helper()
+
+	var resolved []string
+	resolveDigest = func(image config.Image) (string, error) {
+		resolved = append(resolved, image.Reference())
+		return digest, err
+	}
+	t.Cleanup(func() { resolveDigest = resolveImageDigest })
+
+	return &resolved
+}
+
+func TestPinImages(t *testing.T) {
+	latestPostgres := `Deployment:
+  Images:
+    Postgres:
+      Tag: latest
+`
+	previous := `Deployment:
+  Images:
+    ClientServer:
+      Repository: asterizm/client-server
+      Tag: latest
+      Digest: ` + previousDigest + `
+`
+
+	for _, test := range []struct {
+		name     string
+		source   string
+		runtime  string
+		resolve  bool
+		update   bool
+		resolved []string
+		// client-server and postgres digests
+		digests [2]string
+	}{
+		{
+			name:     "first deploy",
+			source:   latestPostgres,
+			resolve:  true,
+			resolved: []string{"asterizm/client-server:latest", "postgres:latest"},
+			digests:  [2]string{resolvedDigest, resolvedDigest},
+		},
+		{
+			name:    "previous pin",
+			runtime: previous,
+			resolve: true,
+			digests: [2]string{previousDigest, ""},
+		},
+		{
+			name:     "update",
+			runtime:  previous,
+			resolve:  true,
+			update:   true,
+			resolved: []string{"asterizm/client-server:latest"},
+			digests:  [2]string{resolvedDigest, ""},
+		},
+		{
+			name:     "another repository",
+			source:   "Deployment:\n  Images:\n    ClientServer:\n      Tag: latest\n      Repository: registry.example.com/client-server\n",
+			runtime:  previous,
+			resolve:  true,
+			resolved: []string{"registry.example.com/client-server:latest"},
+			digests:  [2]string{resolvedDigest, ""},
+		},
+		{
+			name:    "rotation keeps the previous pin",
+			runtime: previous,
+			digests: [2]string{previousDigest, ""},
+		},
+		{
+			name:   "rotation doesn't resolve",
+			source: latestPostgres,
+		},
+		{
+			name:    "fixed tags aren't resolved",
+			source:  "Deployment:\n  Images:\n    ClientServer:\n      Tag: v1.2.3\n",
+			resolve: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			cfg, configFile := imageConfig(t, test.source, test.runtime)
+			resolved := stubResolveDigest(t, resolvedDigest, nil)
+
+			if err := pinImages(cfg, configFile, test.resolve, test.update); err != nil {
+				t.Fatalf("pin: %v", err)
+			}
+
+			if strings.Join(*resolved, ",") != strings.Join(test.resolved, ",") {
+				t.Errorf("resolved %v, want %v", *resolved, test.resolved)
+			}
+
+			for i, name := range []string{config.ClientServerImage, config.PostgresImage} {
+				if digest := cfg.Image(name).Digest; digest != test.digests[i] {
+					t.Errorf("%s digest %q, want %q", name, digest, test.digests[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPinImagesFailsWithoutDigest(t *testing.T) {
+	cfg, configFile := imageConfig(t, "", "")
+	stubResolveDigest(t, "", errors.New("pull asterizm/client-server:latest error: exit status 1"))
+
+	if err := pinImages(cfg, configFile, true, false); err == nil || !strings.Contains(err.Error(), "pull asterizm/client-server:latest") {
+		t.Errorf("error %v", err)
+	}
+
+	if digest := cfg.Image(config.ClientServerImage).Digest; digest != "" {
+		t.Errorf("image is pinned to %s", digest)
+	}
+}
+
+func TestRepositoryDigest(t *testing.T) {
+	for _, test := range []struct {
+		repository  string
+		repoDigests []string
+		digest      string
+	}{
+		{"asterizm/client-server", []string{"asterizm/client-server@" + resolvedDigest}, resolvedDigest},
+		{"asterizm/client-server", []string{"mirror.example.com/client-server@" + previousDigest, "asterizm/client-server@" + resolvedDigest}, resolvedDigest},
+		// docker reports library images without the registry and namespace
+		{"docker.io/library/postgres", []string{"postgres@" + resolvedDigest}, resolvedDigest},
+		{"library/postgres", []string{"postgres@" + resolvedDigest}, resolvedDigest},
+		// the only digest of another repository with the same image id isn't taken
+		{"asterizm/client-server", []string{"mirror.example.com/client-server@" + previousDigest}, ""},
+		{"asterizm/client-server", nil, ""},
+	} {
+		digest, err := repositoryDigest(config.Image{Repository: test.repository, Tag: config.LatestTag}, test.repoDigests)
+		if digest != test.digest || (err == nil) != (test.digest != "") {
+			t.Errorf("%s %v: digest %q, %v, want %q", test.repository, test.repoDigests, digest, err, test.digest)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"asterizm/builder/secrets"
+	"errors"
+	"flag"
+	"fmt"
+)
+
+// keystoreSetCommand stores a secret in the passphrase protected keystore, the keystore is created if absent
+func keystoreSetCommand(args []string) error {
+	flags := flag.NewFlagSet("keystore-set", flag.ExitOnError)
+	keystorePath := flags.String("keystore", "", "Keystore file path")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: keystore-set -keystore <path> <name>, the secret is read from stdin or the terminal")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *keystorePath == "" || flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("keystore path and secret name are required")
+	}
+
+	passphrase, err := secrets.KeystorePassphrase(*keystorePath)
+	if err != nil {
+		return err
+	}
+
+	keystore, err := secrets.OpenKeystore(*keystorePath, passphrase, true)
+	if err != nil {
+		return err
+	}
+
+	secret, err := readSecret(fmt.Sprintf("Secret %s: ", flags.Arg(0)))
+	if err != nil {
+		return err
+	}
+
+	if err := keystore.Set(flags.Arg(0), string(secret)); err != nil {
+		return err
+	}
+
+	if err := keystore.Save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Secret %s is saved, reference it as keystore:%s#%s \n", flags.Arg(0), *keystorePath, flags.Arg(0))
+	return nil
+}
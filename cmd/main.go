@@ -3,8 +3,10 @@ package main
 import (
 	"asterizm/builder/config"
 	"asterizm/builder/dockercompose"
+	"asterizm/builder/preflight"
 	"asterizm/builder/scripts"
-	"bufio"
+	"asterizm/builder/utils"
+	"context"
 	"errors"
 	"flag"
 	"fmt"
@@ -18,10 +20,38 @@ import (
 	"unicode"
 )
 
+// subcommands run instead of the deploy if passed as the first argument
+var subcommands = map[string]func(args []string) error{
+	"encrypt":           encryptCommand,
+	"decrypt":           decryptCommand,
+	"rotate-encryption": rotateEncryptionCommand,
+	"encrypt-file":      encryptFileCommand,
+	"decrypt-file":      decryptFileCommand,
+	"keystore-set":      keystoreSetCommand,
+	"owners":            ownersCommand,
+	"verify":            verifyCommand,
+	"status":            statusCommand,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if subcommand, ok := subcommands[os.Args[1]]; ok {
+			if err := subcommand(os.Args[2:]); err != nil {
+				fmt.Println(redact(capitalize(err.Error())))
+				os.Exit(1)
+			}
+
+			return
+		}
+	}
+
 	help := flag.Bool("help", false, "Show help")
 	configPath := flag.String("f", "", "Config file path")
 	isTest := flag.Bool("test", false, "Use test networks")
+	isLenient := flag.Bool("lenient", false, "Ignore unknown config keys")
+	skipPreflight := flag.Bool("skip-preflight", false, "Don't check RPC endpoints before deploy")
+	checkContracts := flag.Bool("check-contracts", false, "Check that client contracts are deployed before deploy")
+	updateImages := flag.Bool("update-images", false, "Resolve latest image tags again instead of the digests of the previous deploy")
 	flag.Parse()
 
 	if *help {
@@ -45,102 +75,185 @@ func main() {
 		os.Exit(1)
 	}
 
-	refreshedConfig, err := config.ParseAndRefreshConfig(dockercompose.DbHost, *configPath)
+	refreshedConfig, err := config.ParseAndRefreshConfig(dockercompose.DbHost, *configPath, *isLenient)
 	if err != nil {
-		fmt.Printf("Parse config error: %v \n", err)
+		fmt.Printf("Parse config error: %v \n", redact(err.Error()))
 		os.Exit(1)
 	}
 
-	nodeList := make(map[string]config.Node)
-	for k, v := range refreshedConfig.Nodes.List {
-		if v.OwnerPrivateKey == nil {
-			continue
+	// plaintext secrets are known after the config is resolved
+	addConfigSecrets(refreshedConfig)
+
+	for _, warning := range refreshedConfig.Warnings {
+		fmt.Printf("Warning: %s \n", redact(warning))
+	}
+
+	// the container expects encrypted owner keys
+	if err := refreshedConfig.EncryptOwnerKeys(); err != nil {
+		fmt.Printf("Encrypt owner keys error: %v \n", redact(err.Error()))
+		os.Exit(1)
+	}
+	addConfigSecrets(refreshedConfig)
+
+	// chain ids reported by preflight, the network type is guessed by RPC urls without them
+	var chainIds map[string]string
+	if !*skipPreflight {
+		prober := preflight.NewProber(nil, *isTest)
+		results := prober.ProbeConfig(context.Background(), refreshedConfig)
+		chainIds = preflight.ChainIds(results)
+		if *checkContracts && len(preflight.Failed(results)) == 0 {
+			results = append(results, prober.CheckContracts(context.Background(), refreshedConfig)...)
+		}
+		if len(preflight.Failed(results)) == 0 {
+			results = append(results, prober.CheckOwners(context.Background(), refreshedConfig)...)
 		}
+		// RPC urls may hold api keys of the config
+		table := &strings.Builder{}
+		preflight.PrintTable(table, results)
+		fmt.Print(redact(table.String()))
 
-		nodeList[k] = config.Node{
-			OwnerPrivateKey: v.OwnerPrivateKey,
-			OwnerPublicKey:  v.OwnerPublicKey,
-			OwnerWalletType: v.OwnerWalletType,
+		if len(preflight.Failed(results)) > 0 {
+			fmt.Println("Preflight failed, please, check RPC urls, contract addresses and owner keys (use -skip-preflight to deploy anyway)")
+			os.Exit(1)
 		}
+	}
+
+	for _, warning := range refreshedConfig.CheckNetworkType(*isTest, chainIds) {
+		fmt.Printf("Warning: %s \n", redact(warning))
+	}
 
-		if node, ok := refreshedConfig.Nodes.List[k]; ok {
-			node.OwnerPrivateKey = nil
-			node.OwnerPublicKey = nil
-			node.OwnerWalletType = nil
-			refreshedConfig.Nodes.List[k] = node
+	// the runtime config is overwritten below, report changes made outside the builder before that
+	encryptor := refreshedConfig.Encryptor()
+	checkRuntimeConfig(*configPath)
+
+	// owners of the previous runs are registered again, e.g. on a new host or after a db reset
+	nodeList := extractOwners(refreshedConfig)
+	stored, err := config.LoadOwners(*configPath, encryptor)
+	if err != nil {
+		fmt.Printf("Load owners error: %v \n", redact(err.Error()))
+		os.Exit(1)
+	}
+
+	// keys stored by older builders aren't bound to their network
+	if err := config.BindOwnerKeys(encryptor, stored); err != nil {
+		fmt.Printf("Load owners error: %v \n", redact(err.Error()))
+		os.Exit(1)
+	}
+	addOwnerSecrets(encryptor, stored)
+
+	for _, network := range utils.SortedKeys(stored) {
+		if _, ok := nodeList[network]; !ok {
+			fmt.Printf("Owner of %s is taken from %s \n", network, config.OwnersName)
 		}
 	}
+	owners := config.MergeOwners(stored, nodeList)
+
+	if err := pinImages(refreshedConfig, *configPath, true, *updateImages); err != nil {
+		fmt.Printf("Pin images error: %v \n", redact(err.Error()))
+		os.Exit(1)
+	}
 
-	yml, err := yaml.Marshal(refreshedConfig)
+	yml, err := refreshedConfig.Marshal()
 	if err != nil {
-		fmt.Printf("Marshal config error: %v \n", err)
+		fmt.Printf("Marshal config error: %v \n", redact(err.Error()))
 		os.Exit(1)
 	}
 
-	err = os.WriteFile(*configPath, yml, 0644)
+	// the source config is never modified, generated files live in the state directory
+	stateDir := config.StateDir(*configPath)
+	runtimeConfigPath := config.StatePath(*configPath, config.RuntimeConfigName)
+	dockerComposePath := config.StatePath(*configPath, config.DockerComposeName)
+	if err := processScript(scripts.InitStateDir, stateDir); err != nil {
+		fmt.Printf("Please, create %s directory manually \n", stateDir)
+		os.Exit(1)
+	}
+
+	err = config.WriteStateFile(runtimeConfigPath, yml)
 	if err != nil {
-		fmt.Printf("Write config error: %v \n", err)
+		fmt.Printf("Write runtime config error: %v \n", redact(err.Error()))
+		os.Exit(1)
+	}
+
+	if err := config.SignRuntimeConfig(*configPath, yml); err != nil {
+		fmt.Printf("Sign runtime config error: %v \n", redact(err.Error()))
 		os.Exit(1)
 	}
 
-	dockerComposePath := path.Dir(*configPath) + "/docker-compose.yml"
-	if err := processScript(scripts.InitDockerCompose, path.Dir(*configPath)); err != nil {
-		fmt.Printf("Please, create %s file manually \n", dockerComposePath)
+	if len(owners) > 0 {
+		if err := config.SaveOwners(*configPath, encryptor, owners); err != nil {
+			fmt.Printf("Save owners error: %v \n", redact(err.Error()))
+			os.Exit(1)
+		}
+	}
+
+	generatedDockerCompose, err := dockercompose.InitFromConfig("./"+config.RuntimeConfigName, refreshedConfig)
+	if err != nil {
+		fmt.Printf("Generate docker-compose.yml error: %v \n", redact(err.Error()))
 		os.Exit(1)
 	}
 
-	generatedDockerCompose := dockercompose.InitFromConfig("./"+path.Base(*configPath), refreshedConfig)
 	dockerComposeYml, err := yaml.Marshal(generatedDockerCompose)
 	if err != nil {
-		fmt.Printf("Marshal docker-compose.yml error: %v \n", err)
+		fmt.Printf("Marshal docker-compose.yml error: %v \n", redact(err.Error()))
 		os.Exit(1)
 	}
 
-	err = os.WriteFile(dockerComposePath, dockerComposeYml, 0644)
+	err = config.WriteStateFile(dockerComposePath, dockerComposeYml)
 	if err != nil {
-		fmt.Printf("Write docker-compose.yml error: %v \n", err)
+		fmt.Printf("Write docker-compose.yml error: %v \n", redact(err.Error()))
 		os.Exit(1)
 	}
 
-	var commands []string
+	var commands []command
 	if _, ok := generatedDockerCompose.Services[dockercompose.DbHost]; ok {
-		commands = append(commands, fmt.Sprintf("docker compose -f %s up %s -d --wait", dockerComposePath, dockercompose.DbHost))
+		commands = append(commands, shellCommands(fmt.Sprintf("docker compose -f %s up %s -d --wait", dockerComposePath, dockercompose.DbHost))...)
 	}
 
-	commands = append(commands, fmt.Sprintf("docker compose -f %s up %s -d --wait", dockerComposePath, dockercompose.AsterizmConsole))
-	commands = append(commands, fmt.Sprintf("docker exec -t %s ./main migrations/up", dockercompose.AsterizmConsole))
-	commands = append(commands, fmt.Sprintf("docker exec -t %s ./main db/seed", dockercompose.AsterizmConsole))
+	seed := fmt.Sprintf("docker exec -t %s ./main db/seed", dockercompose.AsterizmConsole)
 	if *isTest {
-		commands[len(commands)-1] += " --test" // add test to db/seed
+		seed += " --test"
 	}
 
-	for k, v := range nodeList {
-		if v.OwnerPrivateKey == nil {
-			continue
-		}
+	commands = append(commands, shellCommands(
+		fmt.Sprintf("docker compose -f %s up %s -d --wait", dockerComposePath, dockercompose.AsterizmConsole),
+		fmt.Sprintf("docker exec -t %s ./main migrations/up", dockercompose.AsterizmConsole),
+		seed,
+	)...)
 
-		command := fmt.Sprintf("docker exec -t %s ./main owners/add %s %s", dockercompose.AsterizmConsole, k, *v.OwnerPrivateKey)
-		if v.OwnerPublicKey != nil {
-			command += " " + *v.OwnerPublicKey
-		}
+	registrations, err := ownerCommands(encryptor, owners)
+	if err != nil {
+		fmt.Printf("Owners registration error: %v \n", redact(err.Error()))
+		os.Exit(1)
+	}
+	commands = append(commands, registrations...)
 
-		if v.OwnerWalletType != nil {
-			command += " " + *v.OwnerWalletType
-		}
+	commands = append(commands, shellCommands(fmt.Sprintf("docker compose -f %s up -d --wait", dockerComposePath))...)
 
-		commands = append(commands, command)
+	if err := runCommands(commands); err != nil {
+		os.Exit(1)
 	}
 
-	commands = append(commands, fmt.Sprintf("docker compose -f %s up -d", dockerComposePath))
+	fmt.Println("Finish!")
+}
+
+// extractOwners returns owner keys of the nodes and removes them from the runtime config
+func extractOwners(cfg *config.Config) map[string]config.Node {
+	nodeList := make(map[string]config.Node)
+	for k, v := range cfg.Nodes.List {
+		if v.OwnerPrivateKey == nil {
+			continue
+		}
 
-	for i, command := range commands {
-		if err := processScript(command); err != nil {
-			printCommandsError(commands[i:])
-			os.Exit(1)
+		nodeList[k] = config.Node{
+			OwnerPrivateKey: v.OwnerPrivateKey,
+			OwnerPublicKey:  v.OwnerPublicKey,
+			OwnerWalletType: v.OwnerWalletType,
 		}
+
+		cfg.RemoveOwnerKeys(k)
 	}
 
-	fmt.Println("Finish!")
+	return nodeList
 }
 
 func capitalize(str string) string {
@@ -149,16 +262,6 @@ func capitalize(str string) string {
 	return string(runes)
 }
 
-func printCommandsError(commands []string) {
-	for _, command := range commands {
-		printCommandError(command)
-	}
-}
-
-func printCommandError(command string) {
-	fmt.Printf("Please, run %q manually \n", command)
-}
-
 func checkConfigFileAndDir(configPath string) error {
 	if path.Ext(configPath) != ".yml" && path.Ext(configPath) != ".yaml" {
 		return errors.New("config extension is not supported")
@@ -172,7 +275,7 @@ func checkConfigFileAndDir(configPath string) error {
 		return fmt.Errorf("check config errors: %w", err)
 	}
 
-	if unix.Access(configPath, unix.W_OK) != nil {
+	if unix.Access(configPath, unix.R_OK) != nil {
 		return errors.New("config is not readable")
 	}
 
@@ -191,39 +294,5 @@ func processScript(script string, params ...string) error {
 	cmd := exec.Command("bash")
 	cmd.Stdin = strings.NewReader(script)
 
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		fmt.Println(err)
-	}
-
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
-	}
-
-	err = cmd.Start()
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
-	}
-
-	// print the output of the subprocess
-	go func() {
-		scanner := bufio.NewScanner(stdout)
-		for scanner.Scan() {
-			m := scanner.Text()
-			fmt.Println(m)
-		}
-	}()
-
-	go func() {
-		scanner := bufio.NewScanner(stderr)
-		for scanner.Scan() {
-			m := scanner.Text()
-			fmt.Println(m)
-		}
-	}()
-
-	return cmd.Wait()
+	return runRedacted(cmd)
 }
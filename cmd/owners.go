@@ -0,0 +1,73 @@
+package main
+
+import (
+	"asterizm/builder/config"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// ownersCommand dispatches owners subcommands
+func ownersCommand(args []string) error {
+	if len(args) == 0 || args[0] != "sync" {
+		return errors.New("usage: owners sync -f <config>")
+	}
+
+	return ownersSyncCommand(args[1:])
+}
+
+// ownersSyncCommand registers owners of the owners store in the running console container
+func ownersSyncCommand(args []string) error {
+	flags := flag.NewFlagSet("owners sync", flag.ExitOnError)
+	configPath := flags.String("f", "", "Config file path")
+	flags.Parse(args)
+
+	if *configPath == "" {
+		flags.Usage()
+		return errors.New("config path is required")
+	}
+
+	if err := checkConfigFileAndDir(*configPath); err != nil {
+		return err
+	}
+
+	commands, err := ownersSyncCommands(*configPath)
+	if err != nil {
+		return err
+	}
+
+	if err := runCommands(commands); err != nil {
+		return errors.New("owners registration failed")
+	}
+
+	fmt.Println("Finish!")
+	return nil
+}
+
+// ownersSyncCommands returns registration commands of the stored owners
+func ownersSyncCommands(configPath string) ([]command, error) {
+	if _, err := os.Stat(config.StatePath(configPath, config.RuntimeConfigName)); err != nil {
+		return nil, errors.New("not deployed yet, please, run the deploy first")
+	}
+
+	encryption, err := config.LoadEncryption(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	encryptor := encryption.Encryptor()
+	owners, err := config.LoadOwners(configPath, encryptor)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(owners) == 0 {
+		return nil, fmt.Errorf("no owners in %s", config.StatePath(configPath, config.OwnersName))
+	}
+
+	addSecrets(encryption.Key, encryption.Salt)
+	addOwnerSecrets(encryptor, owners)
+
+	return ownerCommands(encryptor, owners)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"asterizm/builder/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOwnersSyncCommands(t *testing.T) {
+	configFile, encryptor := rotationConfig(t)
+
+	if _, err := ownersSyncCommands(configFile); err == nil || !strings.Contains(err.Error(), "not deployed yet") {
+		t.Errorf("error %v", err)
+	}
+
+	if err := os.WriteFile(config.StatePath(configFile, config.RuntimeConfigName), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	commands, err := ownersSyncCommands(configFile)
+	if err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	// the store keeps the BSC owner only, ETH is in the config
+	if len(commands) != 1 || !strings.HasSuffix(commands[0].line, "owners/add BSC -") {
+		t.Fatalf("commands %v", commands)
+	}
+
+	if plaintext, err := encryptor.Decrypt([]byte(strings.TrimSpace(commands[0].stdin)), "", ""); err != nil || string(plaintext) != testOwnerKey {
+		t.Errorf("stdin decrypts to %q, %v", plaintext, err)
+	}
+}
+
+func TestOwnersSyncCommandsRejects(t *testing.T) {
+	configFile, _ := rotationConfig(t)
+	if err := os.WriteFile(config.StatePath(configFile, config.RuntimeConfigName), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// the store is encrypted with the old key
+	data, _ := os.ReadFile(configFile)
+	if err := os.WriteFile(configFile, []byte(strings.Replace(string(data), "old-encryption-key", "another-encryption-key", 1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ownersSyncCommands(configFile); err == nil || !strings.Contains(err.Error(), "other Utils.Encryption settings") {
+		t.Errorf("error %v", err)
+	}
+
+	// an empty store has nothing to register
+	emptyFile := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.MkdirAll(config.StateDir(emptyFile), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, content := range map[string]string{emptyFile: string(data), config.StatePath(emptyFile, config.RuntimeConfigName): ""} {
+		if err := os.WriteFile(name, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := ownersSyncCommands(emptyFile); err == nil || !strings.Contains(err.Error(), "no owners") {
+		t.Errorf("error %v", err)
+	}
+}
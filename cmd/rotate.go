@@ -0,0 +1,159 @@
+package main
+
+import (
+	"asterizm/builder/config"
+	"asterizm/builder/dockercompose"
+	"asterizm/builder/utils"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// rotateEncryptionCommand replaces Utils.Encryption key and salt, re-encrypts owner keys
+// and re-registers owners in the running console container
+func rotateEncryptionCommand(args []string) error {
+	flags := flag.NewFlagSet("rotate-encryption", flag.ExitOnError)
+	configPath := flags.String("f", "", "Config file path")
+	isLenient := flags.Bool("lenient", false, "Ignore unknown config keys")
+	isDryRun := flags.Bool("dry-run", false, "Show rotated fields without changing anything")
+	flags.Parse(args)
+
+	if *configPath == "" {
+		flags.Usage()
+		return errors.New("config path is required")
+	}
+
+	if err := checkConfigFileAndDir(*configPath); err != nil {
+		return err
+	}
+
+	// the rotated runtime config is signed again, report changes made outside the builder before that
+	checkRuntimeConfig(*configPath)
+
+	encryption, err := config.LoadEncryption(*configPath)
+	if err != nil {
+		return err
+	}
+	addSecrets(encryption.Key, encryption.Salt)
+
+	rotation, err := config.RotateEncryption(dockercompose.DbHost, *configPath, *isLenient)
+	if err != nil {
+		return fmt.Errorf("rotate encryption error: %w", err)
+	}
+
+	// both the old and the new secrets
+	addSecrets(rotation.Secrets...)
+	addConfigSecrets(rotation.Config)
+
+	for _, field := range rotation.Fields {
+		fmt.Printf("Rotate %s \n", field)
+	}
+
+	runtimeConfigPath := config.StatePath(*configPath, config.RuntimeConfigName)
+	_, err = os.Stat(runtimeConfigPath)
+	isDeployed := err == nil
+
+	// the compose file isn't regenerated, keep the pinned images in the runtime config
+	if err := pinImages(rotation.Config, *configPath, false, false); err != nil {
+		return err
+	}
+
+	nodeList := extractOwners(rotation.Config)
+	encryptor := rotation.Config.Encryptor()
+	owners := config.MergeOwners(rotation.Owners, nodeList)
+	addOwnerSecrets(encryptor, owners)
+	runtimeConfig, err := rotation.Config.Marshal()
+	if err != nil {
+		return fmt.Errorf("marshal config error: %w", err)
+	}
+
+	// the console reads the new key on restart, owners are stored encrypted with it
+	var commands []command
+	if isDeployed {
+		commands = append(commands, shellCommands(fmt.Sprintf("docker compose -f %s restart", config.StatePath(*configPath, config.DockerComposeName)))...)
+		registrations, err := ownerCommands(encryptor, owners)
+		if err != nil {
+			return err
+		}
+		commands = append(commands, registrations...)
+	}
+
+	if *isDryRun {
+		for _, c := range commands {
+			printCommand(c)
+		}
+		fmt.Println("Dry run, nothing is changed")
+		return nil
+	}
+
+	// backups keep the old key, which is needed to decrypt the old values
+	suffix := "." + time.Now().Format("20060102150405") + ".bak"
+	if rotation.Source != nil {
+		if err := backupFile(*configPath, *configPath+suffix); err != nil {
+			return err
+		}
+	}
+
+	if isDeployed {
+		if err := backupFile(runtimeConfigPath, runtimeConfigPath+suffix); err != nil {
+			return err
+		}
+	}
+
+	ownersPath := config.StatePath(*configPath, config.OwnersName)
+	if len(rotation.Owners) > 0 {
+		if err := backupFile(ownersPath, ownersPath+suffix); err != nil {
+			return err
+		}
+	}
+
+	if rotation.Source != nil {
+		if err := utils.WriteFileAtomic(*configPath, rotation.Source, 0644); err != nil {
+			return fmt.Errorf("write config error: %w", err)
+		}
+	}
+
+	// owner keys are stored encrypted with the new key
+	if (isDeployed && len(owners) > 0) || len(rotation.Owners) > 0 {
+		if err := config.SaveOwners(*configPath, encryptor, owners); err != nil {
+			return fmt.Errorf("save owners error: %w", err)
+		}
+	}
+
+	if !isDeployed {
+		fmt.Println("Not deployed yet, nothing to re-register")
+		return nil
+	}
+
+	if err := config.WriteStateFile(runtimeConfigPath, runtimeConfig); err != nil {
+		return fmt.Errorf("write runtime config error (restore it from %s): %w", runtimeConfigPath+suffix, err)
+	}
+
+	if err := config.SignRuntimeConfig(*configPath, runtimeConfig); err != nil {
+		return err
+	}
+
+	if err := runCommands(commands); err != nil {
+		return errors.New("owners re-registration failed")
+	}
+
+	fmt.Println("Finish!")
+	return nil
+}
+
+// backupFile copies the file readable only by its owner
+func backupFile(name, backupName string) error {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return fmt.Errorf("backup %s error: %w", name, err)
+	}
+
+	if err := os.WriteFile(backupName, data, 0600); err != nil {
+		return fmt.Errorf("backup %s error: %w", name, err)
+	}
+
+	fmt.Printf("Backup %s to %s \n", name, backupName)
+	return nil
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"asterizm/builder/config"
+	"asterizm/builder/utils"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	testOwnerKey = "0x4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+	testPassword = "test-db-password"
+)
+
+// rotationConfig writes a config with an enc: db password, an encrypted owner key and an owners store
+func rotationConfig(t *testing.T) (string, *utils.Encryptor) {
+	t.Helper()
+
+	encryption := &config.Encryption{Key: "old-encryption-key", Salt: "old-encryption-salt", CipherMethod: config.DefaultCipherMethod}
+	encryptor := encryption.Encryptor()
+
+	password, err := encryptor.Encrypt([]byte(testPassword), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ownerKey, err := encryptor.EncryptFor("ETH", []byte(testOwnerKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "config.yml")
+	source := fmt.Sprintf(`Utils:
+  Encryption:
+    Key: %s
+    Salt: %s
+    CipherMethod: %s
+  Db:
+    Host: db.example.com
+    Port: 5432
+    Name: asterizm
+    User: asterizm
+    Password: enc:%s
+Nodes:
+  List:
+    ETH:
+      RPC: https://rpc.example.com
+      ContractAddress: "0x0000000000000000000000000000000000000001"
+      OwnerPrivateKey: %s
+`, encryption.Key, encryption.Salt, encryption.CipherMethod, password, ownerKey)
+
+	if err := os.WriteFile(configFile, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	storedKey, err := encryptor.EncryptFor("BSC", []byte(testOwnerKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.MkdirAll(config.StateDir(configFile), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	value := string(storedKey)
+	if err := config.SaveOwners(configFile, encryptor, map[string]config.Node{"BSC": {OwnerPrivateKey: &value}}); err != nil {
+		t.Fatal(err)
+	}
+
+	return configFile, encryptor
+}
+
+// checkEncrypted checks that the db password and owner keys of the config and the store decrypt with the encryptor only
+func checkEncrypted(t *testing.T, configFile string, encryptor, other *utils.Encryptor) {
+	t.Helper()
+
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source := &config.Config{}
+	if err := yaml.Unmarshal(data, source); err != nil {
+		t.Fatal(err)
+	}
+
+	password, ok := strings.CutPrefix(source.Utils.Db.Password, "enc:")
+	if !ok {
+		t.Fatalf("%s: db password %q isn't an enc: reference", configFile, source.Utils.Db.Password)
+	}
+
+	if plaintext, err := encryptor.Decrypt([]byte(password), "", ""); err != nil || string(plaintext) != testPassword {
+		t.Errorf("%s: db password is %q, %v", configFile, plaintext, err)
+	}
+
+	if _, err := other.Decrypt([]byte(password), "", ""); err == nil {
+		t.Errorf("%s: db password decrypts with another key", configFile)
+	}
+
+	owners, err := config.LoadOwners(configFile, encryptor)
+	if err != nil {
+		t.Fatalf("%s: %v", configFile, err)
+	}
+
+	if _, err := config.LoadOwners(configFile, other); err == nil {
+		t.Errorf("%s: owners store decrypts with another key", configFile)
+	}
+
+	for network, value := range map[string]*string{"ETH": source.Nodes.List["ETH"].OwnerPrivateKey, "BSC": owners["BSC"].OwnerPrivateKey} {
+		if value == nil {
+			t.Fatalf("%s: %s owner key is absent", configFile, network)
+		}
+
+		if plaintext, err := encryptor.DecryptFor(network, []byte(*value)); err != nil || string(plaintext) != testOwnerKey {
+			t.Errorf("%s: %s owner key is %q, %v", configFile, network, plaintext, err)
+		}
+
+		if _, err := other.DecryptFor(network, []byte(*value)); err == nil {
+			t.Errorf("%s: %s owner key decrypts with another key", configFile, network)
+		}
+	}
+}
+
+func TestRotateEncryptionDryRun(t *testing.T) {
+	configFile, _ := rotationConfig(t)
+	ownersFile := config.StatePath(configFile, config.OwnersName)
+
+	source, _ := os.ReadFile(configFile)
+	owners, _ := os.ReadFile(ownersFile)
+
+	if err := rotateEncryptionCommand([]string{"-f", configFile, "-dry-run"}); err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+
+	if data, _ := os.ReadFile(configFile); string(data) != string(source) {
+		t.Error("config is changed")
+	}
+
+	if data, _ := os.ReadFile(ownersFile); string(data) != string(owners) {
+		t.Error("owners store is changed")
+	}
+
+	backups, _ := filepath.Glob(filepath.Join(filepath.Dir(configFile), "*", "*.bak"))
+	sourceBackups, _ := filepath.Glob(configFile + ".*.bak")
+	if len(backups)+len(sourceBackups) > 0 {
+		t.Errorf("backups %v %v", sourceBackups, backups)
+	}
+}
+
+func TestRotateEncryption(t *testing.T) {
+	configFile, oldEncryptor := rotationConfig(t)
+
+	if err := rotateEncryptionCommand([]string{"-f", configFile}); err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+
+	encryption, err := config.LoadEncryption(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if encryption.Key == "old-encryption-key" || encryption.Salt == "old-encryption-salt" {
+		t.Fatal("encryption key and salt aren't rotated")
+	}
+
+	// every value decrypts with the new key only
+	newEncryptor := encryption.Encryptor()
+	checkEncrypted(t, configFile, newEncryptor, oldEncryptor)
+
+	// backups keep the old key and the values encrypted with it
+	sourceBackups, _ := filepath.Glob(configFile + ".*.bak")
+	ownersBackups, _ := filepath.Glob(config.StatePath(configFile, config.OwnersName) + ".*.bak")
+	if len(sourceBackups) != 1 || len(ownersBackups) != 1 {
+		t.Fatalf("backups %v %v", sourceBackups, ownersBackups)
+	}
+
+	backupFile := filepath.Join(t.TempDir(), "config.yml")
+	for name, backup := range map[string]string{backupFile: sourceBackups[0], config.StatePath(backupFile, config.OwnersName): ownersBackups[0]} {
+		data, err := os.ReadFile(backup)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if info, _ := os.Stat(backup); info.Mode().Perm() != 0600 {
+			t.Errorf("%s mode %o, want 600", backup, info.Mode().Perm())
+		}
+
+		if err := os.MkdirAll(filepath.Dir(name), 0700); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(name, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	checkEncrypted(t, backupFile, oldEncryptor, newEncryptor)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"asterizm/builder/config"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// verifyCommand checks that the runtime config wasn't changed outside the builder
+func verifyCommand(args []string) error {
+	configPath, err := deployedConfig("verify", args)
+	if err != nil {
+		return err
+	}
+
+	if err := config.VerifyRuntimeConfig(configPath); err != nil {
+		printSignatureWarning(configPath, err)
+		return errors.New("verification failed")
+	}
+
+	fmt.Printf("%s matches its signature \n", config.StatePath(configPath, config.RuntimeConfigName))
+	return nil
+}
+
+// statusCommand prints the runtime config signature status and the containers state
+func statusCommand(args []string) error {
+	configPath, err := deployedConfig("status", args)
+	if err != nil {
+		return err
+	}
+
+	if err := config.VerifyRuntimeConfig(configPath); err != nil {
+		printSignatureWarning(configPath, err)
+	} else {
+		fmt.Printf("Runtime config: %s, signature OK \n", config.StatePath(configPath, config.RuntimeConfigName))
+	}
+
+	return processCommand(command{line: fmt.Sprintf("docker compose -f %s ps", config.StatePath(configPath, config.DockerComposeName))})
+}
+
+// deployedConfig parses -f flag and checks that the config is deployed
+func deployedConfig(name string, args []string) (string, error) {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	configPath := flags.String("f", "", "Config file path")
+	flags.Parse(args)
+
+	if *configPath == "" {
+		flags.Usage()
+		return "", errors.New("config path is required")
+	}
+
+	if _, err := os.Stat(config.StatePath(*configPath, config.RuntimeConfigName)); err != nil {
+		return "", errors.New("not deployed yet, please, run the deploy first")
+	}
+
+	return *configPath, nil
+}
+
+// checkRuntimeConfig warns if the deployed runtime config doesn't match its signature
+func checkRuntimeConfig(configPath string) {
+	if _, err := os.Stat(config.StatePath(configPath, config.RuntimeConfigName)); err != nil {
+		return
+	}
+
+	if err := config.VerifyRuntimeConfig(configPath); err != nil {
+		printSignatureWarning(configPath, err)
+	}
+}
+
+func printSignatureWarning(configPath string, err error) {
+	runtimeConfigPath := config.StatePath(configPath, config.RuntimeConfigName)
+	if errors.Is(err, config.ErrNotSigned) {
+		fmt.Printf("Warning: %s is not signed, it is signed by the next deploy \n", runtimeConfigPath)
+		return
+	}
+
+	fmt.Println("!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!")
+	fmt.Printf("WARNING: %s: %s \n", runtimeConfigPath, redact(err.Error()))
+	fmt.Println("WARNING: check RPC urls and contract addresses in it, they decide where funds move")
+	fmt.Println("!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!")
+}
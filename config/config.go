@@ -6,8 +6,13 @@ import (
 	"fmt"
 	"gopkg.in/yaml.v3"
 	"os"
+	"path"
+	"reflect"
 )
 
+// DefaultCipherMethod is used if Utils.Encryption.CipherMethod is empty
+const DefaultCipherMethod = "AES-256-CBC"
+
 type Environment struct {
 	LogLevel string `yaml:"LogLevel"`
 }
@@ -16,6 +21,24 @@ type Encryption struct {
 	Key          string `yaml:"Key"`
 	Salt         string `yaml:"Salt"`
 	CipherMethod string `yaml:"CipherMethod"`
+	Kdf          *Kdf   `yaml:"Kdf,omitempty"`
+}
+
+// Kdf is a derivation of the encryption key, legacy sha256 if absent
+type Kdf struct {
+	Algorithm string `yaml:"Algorithm"`
+
+	// pbkdf2 and argon2id
+	Iterations uint32 `yaml:"Iterations,omitempty"`
+
+	// argon2id only
+	Memory  uint32 `yaml:"Memory,omitempty"`
+	Threads uint8  `yaml:"Threads,omitempty"`
+
+	// scrypt only
+	N uint32 `yaml:"N,omitempty"`
+	R uint32 `yaml:"R,omitempty"`
+	P uint32 `yaml:"P,omitempty"`
 }
 
 type Db struct {
@@ -43,6 +66,12 @@ type Node struct {
 
 	OwnerPrivateKey *string `yaml:"OwnerPrivateKey,omitempty"`
 
+	// optional, compared with the address derived from OwnerPrivateKey
+	OwnerAddress *string `yaml:"OwnerAddress,omitempty"`
+
+	// optional, warn if the owner balance is lower (in native coins)
+	MinOwnerBalance *string `yaml:"MinOwnerBalance,omitempty"`
+
 	// everscale/venom only
 	OwnerPublicKey *string `yaml:"OwnerPublicKey,omitempty"`
 
@@ -77,19 +106,61 @@ type Config struct {
 		PayloadStruct []string        `yaml:"PayloadStruct"`
 		List          map[string]Node `yaml:"List"`
 	} `yaml:"Nodes"`
+	Deployment Deployment `yaml:"Deployment"`
+
+	// not applicable fields found while validation
+	Warnings []string `yaml:"-"`
+
+	document *document
 }
 
-func ParseAndRefreshConfig(dockerDbHost, configFile string) (*Config, error) {
+// ParseAndRefreshConfig parses config and fills generated values
+// values generated by the previous run are taken from the runtime config in the state directory
+// unknown keys are reported as errors, or as warnings in lenient mode
+func ParseAndRefreshConfig(dockerDbHost, configFile string, lenient bool) (*Config, error) {
 	data, err := os.ReadFile(configFile)
 	if err != nil {
 		return nil, fmt.Errorf("error reading file: %w", err)
 	}
 
+	root := &yaml.Node{}
+	if err := yaml.Unmarshal(data, root); err != nil {
+		return nil, fmt.Errorf("error unmarshaling yaml: %w", err)
+	}
+
 	config := &Config{}
-	err = yaml.Unmarshal(data, config)
+	if err := root.Decode(config); err != nil {
+		return nil, fmt.Errorf("error unmarshaling yaml: %w", err)
+	}
+
+	if errs := unknownKeys(path.Base(configFile), root, reflect.TypeOf(config)); len(errs) > 0 {
+		if !lenient {
+			return nil, fmt.Errorf("unknown config keys (use -lenient to ignore them):\n%w", errors.Join(errs...))
+		}
+
+		for _, unknownKey := range errs {
+			config.Warnings = append(config.Warnings, unknownKey.Error())
+		}
+	}
+
+	config.document = newDocument(data, root)
+
+	if err := config.canonicalizeNetworks(); err != nil {
+		return nil, err
+	}
 
+	resolver := config.secretResolver(path.Dir(configFile))
+	if err := config.resolveEncryptionReferences(resolver); err != nil {
+		return nil, err
+	}
+
+	generated, err := loadGenerated(configFile)
 	if err != nil {
-		return nil, fmt.Errorf("error unmarshaling yaml: %w", err)
+		return nil, err
+	}
+
+	if generated.Encryption == nil {
+		generated.Encryption = &Encryption{}
 	}
 
 	if config.Environment.LogLevel == "" {
@@ -102,54 +173,211 @@ func ParseAndRefreshConfig(dockerDbHost, configFile string) (*Config, error) {
 	}
 
 	if config.Utils.Encryption.Key == "" {
-		key, err := utils.GenerateEncryptionString(48)
-		if err != nil {
-			return nil, fmt.Errorf("generate encryption key: %w", err)
+		key := generated.Encryption.Key
+		if key == "" {
+			key, err = utils.GenerateEncryptionString(48)
+			if err != nil {
+				return nil, fmt.Errorf("generate encryption key: %w", err)
+			}
 		}
 		config.Utils.Encryption.Key = key
+		if err := config.document.set([]string{"Utils", "Encryption", "Key"}, key); err != nil {
+			return nil, err
+		}
 	}
 
 	if config.Utils.Encryption.Salt == "" {
-		salt, err := utils.GenerateEncryptionString(48)
-		if err != nil {
-			return nil, fmt.Errorf("generate encryption salt: %w", err)
+		salt := generated.Encryption.Salt
+		if salt == "" {
+			salt, err = utils.GenerateEncryptionString(48)
+			if err != nil {
+				return nil, fmt.Errorf("generate encryption salt: %w", err)
+			}
 		}
 		config.Utils.Encryption.Salt = salt
+		if err := config.document.set([]string{"Utils", "Encryption", "Salt"}, salt); err != nil {
+			return nil, err
+		}
 	}
 
 	if config.Utils.Encryption.CipherMethod == "" {
-		config.Utils.Encryption.CipherMethod = "AES-256-CBC"
+		config.Utils.Encryption.CipherMethod = generated.Encryption.CipherMethod
+		if config.Utils.Encryption.CipherMethod == "" {
+			config.Utils.Encryption.CipherMethod = DefaultCipherMethod
+		}
+		if err := config.document.set([]string{"Utils", "Encryption", "CipherMethod"}, config.Utils.Encryption.CipherMethod); err != nil {
+			return nil, err
+		}
+	}
+
+	// the other secrets may be encrypted with the resolved or generated encryption settings
+	if err := config.resolveReferences(resolver, config.references()); err != nil {
+		return nil, err
 	}
 
 	// generate db
 	if config.Utils.Db == nil {
-		password, err := utils.GeneratePassword(32)
-		if err != nil {
-			return nil, fmt.Errorf("generate db password: %w", err)
+		if generated.Db != nil && generated.Db.Host == dockerDbHost {
+			config.Utils.Db = generated.Db
+		} else {
+			password, err := utils.GeneratePassword(32)
+			if err != nil {
+				return nil, fmt.Errorf("generate db password: %w", err)
+			}
+			config.Utils.Db = &Db{
+				Host:     dockerDbHost,
+				Port:     5432,
+				Name:     "asterizm-cs",
+				User:     "asterizm-cs",
+				Password: password,
+			}
+		}
+
+		if err := config.document.set([]string{"Utils", "Db"}, config.Utils.Db); err != nil {
+			return nil, err
+		}
+	}
+
+	warnings, err := config.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	config.Warnings = append(config.Warnings, warnings...)
+	return config, nil
+}
+
+// LoadEncryption returns Utils.Encryption settings of the config without validating other sections
+// values generated by the previous run are used if the config doesn't define them, nothing is generated
+func LoadEncryption(configFile string) (*Encryption, error) {
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+
+	source := &struct {
+		Utils Utils `yaml:"Utils"`
+	}{}
+
+	if err := yaml.Unmarshal(data, source); err != nil {
+		return nil, fmt.Errorf("error unmarshaling yaml: %w", err)
+	}
+
+	encryption := &Encryption{}
+	if source.Utils.Encryption != nil {
+		encryption = source.Utils.Encryption
+	}
+
+	resolver := newSecretResolver(path.Dir(configFile), func() *utils.Encryptor { return nil })
+	for _, value := range []*string{&encryption.Key, &encryption.Salt} {
+		if *value, err = resolveEncryptionValue(resolver, *value); err != nil {
+			return nil, fmt.Errorf("Utils.Encryption: %w", err)
 		}
-		config.Utils.Db = &Db{
-			Host:     dockerDbHost,
-			Port:     5432,
-			Name:     "asterizm-cs",
-			User:     "asterizm-cs",
-			Password: password,
+	}
+
+	generated, err := loadGenerated(configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if generated.Encryption != nil {
+		if encryption.Key == "" {
+			encryption.Key = generated.Encryption.Key
+		}
+		if encryption.Salt == "" {
+			encryption.Salt = generated.Encryption.Salt
+		}
+		if encryption.CipherMethod == "" {
+			encryption.CipherMethod = generated.Encryption.CipherMethod
+		}
+		if encryption.Kdf == nil {
+			encryption.Kdf = generated.Encryption.Kdf
 		}
 	}
 
-	if len(config.Nodes.List) == 0 {
-		return nil, errors.New("please, fill Nodes.List")
+	if encryption.CipherMethod == "" {
+		encryption.CipherMethod = DefaultCipherMethod
 	}
 
-	newList := make(map[string]Node, len(config.Nodes.List))
+	return encryption, nil
+}
+
+// canonicalizeNetworks converts network keys to the upper case registry codes
+// keys which differ only by case are reported as duplicates
+func (c *Config) canonicalizeNetworks() error {
+	list := make(map[string]Node, len(c.Nodes.List))
+	sources := make(map[string]string, len(c.Nodes.List))
 
-	for key, node := range config.Nodes.List {
-		newList[key] = node
+	var errs []error
+	for _, key := range utils.SortedKeys(c.Nodes.List) {
+		canonical := canonicalNetwork(key)
 
-		if node.RPC == "" {
-			return nil, fmt.Errorf("please, fill Nodes.List.%s.RPC", key)
+		if source, ok := sources[canonical]; ok {
+			errs = append(errs, fmt.Errorf("duplicate network Nodes.List.%s: %q and %q", canonical, source, key))
+			continue
+		}
+
+		sources[canonical] = key
+		list[canonical] = c.Nodes.List[key]
+
+		if canonical != key {
+			c.document.rename([]string{"Nodes", "List", key}, canonical)
 		}
 	}
 
-	config.Nodes.List = newList
-	return config, nil
+	c.Nodes.List = list
+	return errors.Join(errs...)
+}
+
+// RemoveOwnerKeys drops owner credentials of the network from the runtime config
+func (c *Config) RemoveOwnerKeys(network string) {
+	node, ok := c.Nodes.List[network]
+	if !ok {
+		return
+	}
+
+	node.OwnerPrivateKey = nil
+	node.OwnerPublicKey = nil
+	node.OwnerWalletType = nil
+	c.Nodes.List[network] = node
+
+	for _, key := range []string{"OwnerPrivateKey", "OwnerPublicKey", "OwnerWalletType"} {
+		c.document.remove([]string{"Nodes", "List", network, key})
+	}
+}
+
+// Secrets returns secret values of the config which must not be printed
+// owner keys are returned both encrypted and decrypted
+func (c *Config) Secrets() []string {
+	var result []string
+
+	if c.Utils.Encryption != nil {
+		result = append(result, c.Utils.Encryption.Key, c.Utils.Encryption.Salt)
+	}
+
+	if c.Utils.Db != nil {
+		result = append(result, c.Utils.Db.Password)
+	}
+
+	for _, key := range utils.SortedKeys(c.Nodes.List) {
+		node := c.Nodes.List[key]
+		if node.OwnerPrivateKey != nil {
+			result = append(result, *node.OwnerPrivateKey)
+			if plaintext, err := c.DecryptOwnerKey(key); err == nil {
+				result = append(result, plaintext)
+			}
+		}
+
+		if node.Fireblocks != nil {
+			result = append(result, node.Fireblocks.ApiKey)
+		}
+	}
+
+	return result
+}
+
+// Marshal returns runtime config: the source config with applied changes
+// comments, key order and unknown keys are preserved
+func (c *Config) Marshal() ([]byte, error) {
+	return c.document.render()
 }
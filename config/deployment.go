@@ -0,0 +1,256 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"regexp"
+	"time"
+)
+
+const (
+	ClientServerImage = "ClientServer"
+	PostgresImage     = "Postgres"
+
+	// LatestTag is resolved to a digest while deploy
+	LatestTag = "latest"
+)
+
+var (
+	defaultImages = map[string]Image{
+		ClientServerImage: {Repository: "asterizm/client-server", Tag: LatestTag},
+		PostgresImage:     {Repository: "postgres", Tag: "15-alpine"},
+	}
+
+	repositoryPattern = regexp.MustCompile(`^[a-z0-9]+([._/:-][a-z0-9]+)*$`)
+	tagPattern        = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+	digestPattern     = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+	defaultHealthCheck = HealthCheck{
+		Interval:    "10s",
+		Timeout:     "5s",
+		Retries:     3,
+		StartPeriod: "10s",
+	}
+)
+
+// Image is a docker image, the digest pins the exact version of the tag
+type Image struct {
+	Repository string `yaml:"Repository,omitempty"`
+	Tag        string `yaml:"Tag,omitempty"`
+	Digest     string `yaml:"Digest,omitempty"`
+}
+
+// HealthCheck is a docker healthcheck of the generated services
+type HealthCheck struct {
+	Interval    string `yaml:"Interval,omitempty"`
+	Timeout     string `yaml:"Timeout,omitempty"`
+	Retries     int    `yaml:"Retries,omitempty"`
+	StartPeriod string `yaml:"StartPeriod,omitempty"`
+
+	// console, cron and scanners only, postgres is checked with pg_isready
+	// the client-server image has no health endpoint, so they aren't checked without the test
+	Test []string `yaml:"Test,omitempty"`
+}
+
+type Deployment struct {
+	Images struct {
+		ClientServer *Image `yaml:"ClientServer,omitempty"`
+		Postgres     *Image `yaml:"Postgres,omitempty"`
+	} `yaml:"Images"`
+	HealthCheck *HealthCheck     `yaml:"HealthCheck,omitempty"`
+	Resources   ServiceResources `yaml:"Resources"`
+}
+
+// Reference returns repository:tag@digest of the image
+func (i Image) Reference() string {
+	reference := i.Repository
+	if i.Tag != "" {
+		reference += ":" + i.Tag
+	}
+
+	if i.Digest != "" {
+		reference += "@" + i.Digest
+	}
+
+	return reference
+}
+
+// IsFloating checks that the image isn't pinned, so every pull may change its version
+func (i Image) IsFloating() bool {
+	return i.Digest == "" && (i.Tag == "" || i.Tag == LatestTag)
+}
+
+func (i Image) validate(name string) error {
+	var errs []error
+
+	if !repositoryPattern.MatchString(i.Repository) {
+		errs = append(errs, fmt.Errorf("Deployment.Images.%s.Repository: bad repository %q", name, i.Repository))
+	}
+
+	if i.Tag != "" && !tagPattern.MatchString(i.Tag) {
+		errs = append(errs, fmt.Errorf("Deployment.Images.%s.Tag: bad tag %q", name, i.Tag))
+	}
+
+	if i.Digest != "" && !digestPattern.MatchString(i.Digest) {
+		errs = append(errs, fmt.Errorf("Deployment.Images.%s.Digest: digest format is sha256:<64 hex chars>", name))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Image returns the image settings with defaults
+func (c *Config) Image(name string) Image {
+	image := defaultImages[name]
+
+	var configured *Image
+	switch name {
+	case ClientServerImage:
+		configured = c.Deployment.Images.ClientServer
+	case PostgresImage:
+		configured = c.Deployment.Images.Postgres
+	}
+
+	if configured == nil {
+		return image
+	}
+
+	// another repository doesn't inherit the default tag
+	if configured.Repository != "" && configured.Repository != image.Repository {
+		image = Image{Repository: configured.Repository, Tag: LatestTag}
+	}
+
+	if configured.Tag != "" {
+		image.Tag = configured.Tag
+	}
+	image.Digest = configured.Digest
+
+	return image
+}
+
+// HealthCheck returns the healthcheck settings with defaults
+func (c *Config) HealthCheck() HealthCheck {
+	healthCheck := defaultHealthCheck
+	configured := c.Deployment.HealthCheck
+	if configured == nil {
+		return healthCheck
+	}
+
+	if configured.Interval != "" {
+		healthCheck.Interval = configured.Interval
+	}
+
+	if configured.Timeout != "" {
+		healthCheck.Timeout = configured.Timeout
+	}
+
+	if configured.Retries != 0 {
+		healthCheck.Retries = configured.Retries
+	}
+
+	if configured.StartPeriod != "" {
+		healthCheck.StartPeriod = configured.StartPeriod
+	}
+
+	if len(configured.Test) > 0 {
+		healthCheck.Test = configured.Test
+	}
+
+	return healthCheck
+}
+
+func (h HealthCheck) validate() error {
+	var errs []error
+
+	for _, field := range []struct {
+		name  string
+		value string
+	}{
+		{name: "Interval", value: h.Interval},
+		{name: "Timeout", value: h.Timeout},
+		{name: "StartPeriod", value: h.StartPeriod},
+	} {
+		if duration, err := time.ParseDuration(field.value); err != nil || duration <= 0 {
+			errs = append(errs, fmt.Errorf("Deployment.HealthCheck.%s: bad duration %q, use e.g. 10s", field.name, field.value))
+		}
+	}
+
+	if h.Retries <= 0 {
+		errs = append(errs, errors.New("Deployment.HealthCheck.Retries: must be positive"))
+	}
+
+	if len(h.Test) > 0 && !isHealthCheckTest(h.Test) {
+		errs = append(errs, errors.New(`Deployment.HealthCheck.Test: must start with "CMD" or "CMD-SHELL" followed by the command`))
+	}
+
+	return errors.Join(errs...)
+}
+
+func isHealthCheckTest(test []string) bool {
+	return len(test) > 1 && (test[0] == "CMD" || test[0] == "CMD-SHELL")
+}
+
+// PinImage records the digest of the image in the runtime config
+func (c *Config) PinImage(name, digest string) error {
+	image := c.Image(name)
+	image.Digest = digest
+
+	switch name {
+	case ClientServerImage:
+		c.Deployment.Images.ClientServer = &image
+	case PostgresImage:
+		c.Deployment.Images.Postgres = &image
+	default:
+		return fmt.Errorf("unknown image %s", name)
+	}
+
+	return c.document.set([]string{"Deployment", "Images", name}, image)
+}
+
+// PinnedImages returns images recorded in the runtime config by the previous run
+func PinnedImages(configFile string) (map[string]Image, error) {
+	data, err := os.ReadFile(StatePath(configFile, RuntimeConfigName))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return map[string]Image{}, nil
+		}
+
+		return nil, fmt.Errorf("error reading runtime config: %w", err)
+	}
+
+	runtime := &Config{}
+	if err := yaml.Unmarshal(data, runtime); err != nil {
+		return nil, fmt.Errorf("error unmarshaling runtime config: %w", err)
+	}
+
+	pinned := make(map[string]Image)
+	for _, name := range []string{ClientServerImage, PostgresImage} {
+		if image := runtime.Image(name); image.Digest != "" {
+			pinned[name] = image
+		}
+	}
+
+	return pinned, nil
+}
+
+// validateDeployment checks the configured images
+func (c *Config) validateDeployment() error {
+	var errs []error
+
+	for _, name := range []string{ClientServerImage, PostgresImage} {
+		if err := c.Image(name).validate(name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := c.HealthCheck().validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := c.validateResources(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+var testDigest = "sha256:" + strings.Repeat("ab", 32)
+
+func TestPinImage(t *testing.T) {
+	cfg := parseTestConfig(t, `Deployment:
+  Images:
+    ClientServer:
+      Repository: registry.example.com/client-server
+`)
+
+	if err := cfg.PinImage(ClientServerImage, testDigest); err != nil {
+		t.Fatalf("pin: %v", err)
+	}
+
+	// another repository gets the latest tag, the digest pins it
+	want := Image{Repository: "registry.example.com/client-server", Tag: LatestTag, Digest: testDigest}
+	if image := cfg.Image(ClientServerImage); image != want {
+		t.Errorf("image %+v, want %+v", image, want)
+	}
+
+	data, err := cfg.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runtime := parseTestConfig(t, string(data))
+	if image := runtime.Image(ClientServerImage); image != want {
+		t.Errorf("runtime config image %+v, want %+v", image, want)
+	}
+
+	if err := cfg.PinImage("Redis", testDigest); err == nil {
+		t.Error("unknown image is pinned")
+	}
+}
+
+func TestPinnedImages(t *testing.T) {
+	configFile := ownerStoreConfig(t)
+
+	pinned, err := PinnedImages(configFile)
+	if err != nil || len(pinned) != 0 {
+		t.Fatalf("not deployed config pins %v, %v", pinned, err)
+	}
+
+	configFile = deployTestConfig(t, `Deployment:
+  Images:
+    ClientServer:
+      Repository: asterizm/client-server
+      Tag: latest
+      Digest: `+testDigest+`
+    Postgres:
+      Tag: 16-alpine
+`)
+
+	pinned, err = PinnedImages(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// images without a digest aren't pinned
+	want := Image{Repository: "asterizm/client-server", Tag: LatestTag, Digest: testDigest}
+	if len(pinned) != 1 || pinned[ClientServerImage] != want {
+		t.Errorf("pinned %v, want %s only", pinned, want.Reference())
+	}
+
+	configFile = deployTestConfig(t, "Deployment: [")
+	if _, err := PinnedImages(configFile); err == nil {
+		t.Error("broken runtime config is accepted")
+	}
+}
+
+func TestHealthCheckValidate(t *testing.T) {
+	for _, test := range []struct {
+		healthCheck *HealthCheck
+		err         string
+	}{
+		{healthCheck: nil},
+		// zero fields are the defaults
+		{healthCheck: &HealthCheck{}},
+		{healthCheck: &HealthCheck{Retries: 5, Test: []string{"CMD", "true"}}},
+		{healthCheck: &HealthCheck{Retries: -1}, err: "Deployment.HealthCheck.Retries: must be positive"},
+		{healthCheck: &HealthCheck{Interval: "10"}, err: "Deployment.HealthCheck.Interval: bad duration"},
+		{healthCheck: &HealthCheck{Timeout: "-5s"}, err: "Deployment.HealthCheck.Timeout: bad duration"},
+		{healthCheck: &HealthCheck{Test: []string{"true"}}, err: "Deployment.HealthCheck.Test"},
+		{healthCheck: &HealthCheck{Test: []string{"CMD"}}, err: "Deployment.HealthCheck.Test"},
+	} {
+		cfg := &Config{}
+		cfg.Deployment.HealthCheck = test.healthCheck
+
+		err := cfg.HealthCheck().validate()
+		if (test.err == "" && err != nil) || (test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err))) {
+			t.Errorf("%+v: error %v, want %q", test.healthCheck, err, test.err)
+		}
+	}
+}
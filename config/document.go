@@ -0,0 +1,455 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"sort"
+	"strings"
+)
+
+const defaultIndent = 2
+
+// document keeps parsed yaml tree together with the source text
+// edits are applied to the tree and rendered back as text patches,
+// so untouched lines stay byte-for-byte the same
+type document struct {
+	lines []string
+	root  *yaml.Node
+	// indent step of the source
+	indent int
+	// last source line of every parsed node
+	ends map[*yaml.Node]int
+	// scalars with changed values
+	changed map[*yaml.Node]*yaml.Node
+	// null values replaced by mappings, key by value
+	replaced map[*yaml.Node]*yaml.Node
+	// renamed keys with their source names
+	renamed map[*yaml.Node]string
+	// removed key/value pairs
+	removed [][2]*yaml.Node
+	// source can't be patched, the whole tree is encoded
+	fallback bool
+}
+
+type lineEdit struct {
+	// replaced lines are [start, end), 0-based
+	start int
+	end   int
+	lines []string
+}
+
+func newDocument(data []byte, root *yaml.Node) *document {
+	d := &document{
+		lines:    strings.SplitAfter(string(data), "\n"),
+		root:     root,
+		indent:   defaultIndent,
+		ends:     make(map[*yaml.Node]int),
+		changed:  make(map[*yaml.Node]*yaml.Node),
+		replaced: make(map[*yaml.Node]*yaml.Node),
+		renamed:  make(map[*yaml.Node]string),
+	}
+
+	if d.lines[len(d.lines)-1] == "" {
+		d.lines = d.lines[:len(d.lines)-1]
+	}
+
+	d.calculateEnds(root)
+	d.detectIndent(d.mapping())
+
+	return d
+}
+
+// set creates or replaces value by key path
+func (d *document) set(path []string, value any) error {
+	valueNode := &yaml.Node{}
+	if err := valueNode.Encode(value); err != nil {
+		return fmt.Errorf("encode %s: %w", strings.Join(path, "."), err)
+	}
+
+	node := d.mapping()
+	if node == nil {
+		return fmt.Errorf("config root is not a mapping")
+	}
+
+	for i, key := range path {
+		if node.Style&yaml.FlowStyle != 0 {
+			d.fallback = true
+		}
+
+		keyNode, child := findKey(node, key)
+		if keyNode == nil {
+			if i < len(path)-1 {
+				child = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			} else {
+				child = valueNode
+			}
+
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, child)
+			node = child
+			continue
+		}
+
+		if i == len(path)-1 {
+			d.replace(child, valueNode)
+			return nil
+		}
+
+		if child.Kind != yaml.MappingNode {
+			if child.Kind != yaml.ScalarNode || child.Tag != "!!null" {
+				return fmt.Errorf("%s is not a mapping", strings.Join(path[:i+1], "."))
+			}
+
+			d.replace(child, &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"})
+		}
+
+		node = child
+	}
+
+	return nil
+}
+
+// remove deletes key path if it exists
+func (d *document) remove(path []string) {
+	node := d.mapping()
+
+	for i, key := range path {
+		if node == nil || node.Kind != yaml.MappingNode {
+			return
+		}
+
+		keyNode, child := findKey(node, key)
+		if keyNode == nil {
+			return
+		}
+
+		if i < len(path)-1 {
+			node = child
+			continue
+		}
+
+		if node.Style&yaml.FlowStyle != 0 {
+			d.fallback = true
+		}
+
+		for j := 0; j+1 < len(node.Content); j += 2 {
+			if node.Content[j] == keyNode {
+				node.Content = append(node.Content[:j], node.Content[j+2:]...)
+				break
+			}
+		}
+
+		if keyNode.Line > 0 {
+			d.removed = append(d.removed, [2]*yaml.Node{keyNode, child})
+		}
+	}
+}
+
+// rename changes the last key of the path if it exists
+func (d *document) rename(path []string, name string) {
+	node := d.mapping()
+
+	for i, key := range path {
+		if node == nil || node.Kind != yaml.MappingNode {
+			return
+		}
+
+		keyNode, child := findKey(node, key)
+		if keyNode == nil {
+			return
+		}
+
+		if i < len(path)-1 {
+			node = child
+			continue
+		}
+
+		if _, ok := d.renamed[keyNode]; !ok && keyNode.Line > 0 {
+			d.renamed[keyNode] = keyNode.Value
+		}
+
+		// keys with inline values or special styles can't be patched in place
+		if keyNode.Style != 0 || node.Style&yaml.FlowStyle != 0 || child.Line == keyNode.Line {
+			d.fallback = true
+		}
+
+		keyNode.Value = name
+	}
+}
+
+// render returns source text with applied edits
+func (d *document) render() ([]byte, error) {
+	if d.fallback {
+		return d.encode()
+	}
+
+	var edits []lineEdit
+	for _, pair := range d.removed {
+		start := pair[0].Line - 1
+		// drop comments describing the removed key
+		comments := 0
+		if pair[0].HeadComment != "" {
+			comments = strings.Count(pair[0].HeadComment, "\n") + 1
+		}
+		for ; comments > 0 && start > 0 && strings.HasPrefix(strings.TrimSpace(d.lines[start-1]), "#"); comments-- {
+			start--
+		}
+
+		edits = append(edits, lineEdit{start: start, end: d.ends[pair[1]]})
+	}
+
+	if err := d.collectEdits(d.mapping(), &edits); err != nil {
+		return nil, err
+	}
+
+	if d.fallback {
+		return d.encode()
+	}
+
+	// apply from the bottom, so line numbers of the next edits stay valid
+	sort.SliceStable(edits, func(i, j int) bool {
+		if edits[i].start != edits[j].start {
+			return edits[i].start > edits[j].start
+		}
+		return edits[i].end > edits[j].end
+	})
+
+	lines := append([]string(nil), d.lines...)
+	for _, edit := range edits {
+		if edit.end == len(lines) && len(lines) > 0 && len(edit.lines) > 0 && !strings.HasSuffix(lines[len(lines)-1], "\n") {
+			lines[len(lines)-1] += "\n"
+		}
+
+		lines = append(lines[:edit.start], append(append([]string(nil), edit.lines...), lines[edit.end:]...)...)
+	}
+
+	return []byte(strings.Join(lines, "")), nil
+}
+
+func (d *document) collectEdits(node *yaml.Node, edits *[]lineEdit) error {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	var added []*yaml.Node
+	indent := -1
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, value := node.Content[i], node.Content[i+1]
+		if keyNode.Line == 0 {
+			added = append(added, keyNode, value)
+			continue
+		}
+
+		indent = keyNode.Column - 1
+
+		if original, ok := d.renamed[keyNode]; ok {
+			line := d.lines[keyNode.Line-1]
+			start := keyNode.Column - 1
+			if !strings.HasPrefix(line[start:], original) {
+				d.fallback = true
+				return nil
+			}
+
+			line = line[:start] + keyNode.Value + line[start+len(original):]
+			*edits = append(*edits, lineEdit{start: keyNode.Line - 1, end: keyNode.Line, lines: []string{line}})
+		}
+
+		if original, ok := d.changed[value]; ok {
+			line, err := d.replaceValueLine(keyNode, original, value)
+			if err != nil {
+				return err
+			}
+			*edits = append(*edits, lineEdit{start: keyNode.Line - 1, end: keyNode.Line, lines: []string{line}})
+			continue
+		}
+
+		if original, ok := d.replaced[value]; ok {
+			line, err := d.replaceValueLine(keyNode, original, nil)
+			if err != nil {
+				return err
+			}
+
+			block, err := d.renderBlock(value, keyNode.Column-1+d.indent)
+			if err != nil {
+				return err
+			}
+
+			*edits = append(*edits, lineEdit{start: keyNode.Line - 1, end: keyNode.Line, lines: append([]string{line}, block...)})
+			continue
+		}
+
+		if err := d.collectEdits(value, edits); err != nil {
+			return err
+		}
+	}
+
+	if len(added) == 0 {
+		return nil
+	}
+
+	if indent < 0 {
+		// all source keys were removed, so the mapping position is unknown
+		d.fallback = true
+		return nil
+	}
+
+	block, err := d.renderBlock(&yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: added}, indent)
+	if err != nil {
+		return err
+	}
+
+	end := d.ends[node]
+	*edits = append(*edits, lineEdit{start: end, end: end, lines: block})
+
+	return nil
+}
+
+// replaceValueLine rebuilds "Key: value # comment" line with the new value
+func (d *document) replaceValueLine(keyNode, original, value *yaml.Node) (string, error) {
+	line := strings.TrimRight(d.lines[keyNode.Line-1], "\r\n")
+	if original.Line != keyNode.Line || original.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+		d.fallback = true
+		return line, nil
+	}
+
+	colon := strings.Index(line[keyNode.Column-1:], ":")
+	if colon < 0 {
+		d.fallback = true
+		return line, nil
+	}
+
+	result := line[:keyNode.Column+colon]
+	if value != nil {
+		text, err := yaml.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		result += " " + strings.TrimSuffix(string(text), "\n")
+	}
+
+	for _, comment := range []string{keyNode.LineComment, original.LineComment} {
+		if comment != "" {
+			result += " " + comment
+		}
+	}
+
+	return result + "\n", nil
+}
+
+func (d *document) renderBlock(node *yaml.Node, indent int) ([]string, error) {
+	buf := &bytes.Buffer{}
+	encoder := yaml.NewEncoder(buf)
+	encoder.SetIndent(d.indent)
+
+	if err := encoder.Encode(node); err != nil {
+		return nil, err
+	}
+
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	prefix := strings.Repeat(" ", indent)
+	lines := strings.SplitAfter(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = prefix + strings.TrimSuffix(line, "\n") + "\n"
+	}
+
+	return lines, nil
+}
+
+func (d *document) encode() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	encoder := yaml.NewEncoder(buf)
+	encoder.SetIndent(d.indent)
+
+	if err := encoder.Encode(d.root); err != nil {
+		return nil, err
+	}
+
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// replace swaps node value in place, so parents keep the same pointer
+func (d *document) replace(node, value *yaml.Node) {
+	_, isChanged := d.changed[node]
+	_, isReplaced := d.replaced[node]
+
+	if node.Line > 0 && !isChanged && !isReplaced {
+		original := *node
+		if value.Kind == yaml.ScalarNode {
+			d.changed[node] = &original
+		} else {
+			d.replaced[node] = &original
+		}
+	}
+
+	line := node.Line
+	*node = *value
+	node.Line = line
+}
+
+func (d *document) mapping() *yaml.Node {
+	node := d.root
+	if node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			return nil
+		}
+		node = node.Content[0]
+	}
+
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	return node
+}
+
+func (d *document) calculateEnds(node *yaml.Node) int {
+	end := node.Line
+	switch {
+	case node.Kind == yaml.ScalarNode && node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0:
+		end += strings.Count(strings.TrimSuffix(node.Value, "\n"), "\n") + 1
+	case node.Kind == yaml.ScalarNode && node.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0:
+		end += strings.Count(node.Value, "\n")
+	}
+
+	for _, child := range node.Content {
+		if childEnd := d.calculateEnds(child); childEnd > end {
+			end = childEnd
+		}
+	}
+
+	d.ends[node] = end
+	return end
+}
+
+func (d *document) detectIndent(node *yaml.Node) {
+	if node == nil {
+		return
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		child := node.Content[i+1]
+		if child.Kind == yaml.MappingNode && child.Style&yaml.FlowStyle == 0 && len(child.Content) > 0 {
+			if step := child.Content[0].Column - node.Content[i].Column; step > 0 {
+				d.indent = step
+				return
+			}
+		}
+	}
+}
+
+func findKey(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], node.Content[i+1]
+		}
+	}
+
+	return nil, nil
+}
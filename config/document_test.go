@@ -0,0 +1,193 @@
+package config
+
+import (
+	"gopkg.in/yaml.v3"
+	"testing"
+)
+
+func TestDocumentRender(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		source   string
+		edit     func(d *document) error
+		expected string
+	}{
+		{
+			name: "scalar set",
+			source: `# builder config
+Utils:
+  Db:
+    Password: old # db password
+
+    Host:   db
+`,
+			edit: func(d *document) error { return d.set([]string{"Utils", "Db", "Password"}, "new") },
+			expected: `# builder config
+Utils:
+  Db:
+    Password: new # db password
+
+    Host:   db
+`,
+		},
+		{
+			name: "key removal with its head comment",
+			source: `Nodes:
+  List:
+    # ethereum
+    # mainnet
+    ETH:
+      RPC: https://eth.example.com
+
+    # solana
+    SOL:
+      RPC: https://sol.example.com
+`,
+			edit: func(d *document) error {
+				d.remove([]string{"Nodes", "List", "ETH"})
+				return nil
+			},
+			expected: `Nodes:
+  List:
+
+    # solana
+    SOL:
+      RPC: https://sol.example.com
+`,
+		},
+		{
+			name: "rename",
+			source: `Nodes:
+  List:
+    Ton: # toncenter
+      RPC: https://ton.example.com
+`,
+			edit: func(d *document) error {
+				d.rename([]string{"Nodes", "List", "Ton"}, "TON")
+				return nil
+			},
+			expected: `Nodes:
+  List:
+    TON: # toncenter
+      RPC: https://ton.example.com
+`,
+		},
+		{
+			name: "flow style map",
+			source: `Nodes:
+  List:
+    eth: {RPC: https://eth.example.com}
+`,
+			edit: func(d *document) error {
+				d.rename([]string{"Nodes", "List", "eth"}, "ETH")
+				return d.set([]string{"Nodes", "List", "ETH", "RPC"}, "https://rpc.example.com")
+			},
+			// flow maps can't be patched by lines, the whole tree is encoded
+			expected: `Nodes:
+  List:
+    ETH: {RPC: 'https://rpc.example.com'}
+`,
+		},
+		{
+			name: "block scalar",
+			source: `Deployment:
+  Note: |
+    first line
+    Key: not a key
+  Key: old
+`,
+			edit: func(d *document) error { return d.set([]string{"Deployment", "Key"}, "new") },
+			expected: `Deployment:
+  Note: |
+    first line
+    Key: not a key
+  Key: new
+`,
+		},
+		{
+			name: "block scalar removal",
+			source: `Deployment:
+  Note: |
+    first line
+    second line
+  Key: value
+`,
+			edit: func(d *document) error {
+				d.remove([]string{"Deployment", "Note"})
+				return nil
+			},
+			expected: `Deployment:
+  Key: value
+`,
+		},
+		{
+			name:   "missing trailing newline",
+			source: "Utils:\n  Db:\n    Host: db",
+			edit:   func(d *document) error { return d.set([]string{"Utils", "Db", "Password"}, "secret") },
+			expected: `Utils:
+  Db:
+    Host: db
+    Password: secret
+`,
+		},
+		{
+			name: "absent utils block",
+			source: `Nodes:
+    List:
+        ETH:
+            RPC: https://eth.example.com # rpc
+`,
+			edit: func(d *document) error {
+				if err := d.set([]string{"Utils", "Encryption", "Key"}, "key"); err != nil {
+					return err
+				}
+				return d.set([]string{"Utils", "Encryption", "Salt"}, "salt")
+			},
+			expected: `Nodes:
+    List:
+        ETH:
+            RPC: https://eth.example.com # rpc
+Utils:
+    Encryption:
+        Key: key
+        Salt: salt
+`,
+		},
+		{
+			name: "null value replaced by a mapping",
+			source: `Utils:
+  Db: # database
+Nodes:
+  List: {}
+`,
+			edit: func(d *document) error { return d.set([]string{"Utils", "Db", "Password"}, "secret") },
+			expected: `Utils:
+  Db: # database
+    Password: secret
+Nodes:
+  List: {}
+`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			root := &yaml.Node{}
+			if err := yaml.Unmarshal([]byte(test.source), root); err != nil {
+				t.Fatal(err)
+			}
+
+			d := newDocument([]byte(test.source), root)
+			if err := test.edit(d); err != nil {
+				t.Fatalf("edit: %v", err)
+			}
+
+			rendered, err := d.render()
+			if err != nil {
+				t.Fatalf("render: %v", err)
+			}
+
+			if string(rendered) != test.expected {
+				t.Errorf("rendered:\n%s\nwant:\n%s", rendered, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"asterizm/builder/secrets"
+	"asterizm/builder/utils"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// encryptedScheme marks inline values encrypted with Utils.Encryption settings
+const encryptedScheme = "enc"
+
+// ${VAR}, ${VAR:-default} or escaped $${...}
+var variablePattern = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+type reference struct {
+	path  []string
+	value *string
+	// writes the resolved value back to its map entry, nil if value points into the config
+	store func()
+}
+
+// encryptionReferences returns Utils.Encryption values which support interpolation
+// they are resolved before the other values, which may be encrypted with them
+func (c *Config) encryptionReferences() []reference {
+	if c.Utils.Encryption == nil {
+		return nil
+	}
+
+	return []reference{
+		{path: []string{"Utils", "Encryption", "Key"}, value: &c.Utils.Encryption.Key},
+		{path: []string{"Utils", "Encryption", "Salt"}, value: &c.Utils.Encryption.Salt},
+	}
+}
+
+// references returns other config secrets which support interpolation
+func (c *Config) references() []reference {
+	var result []reference
+
+	if c.Utils.Db != nil {
+		result = append(result, reference{path: []string{"Utils", "Db", "Password"}, value: &c.Utils.Db.Password})
+	}
+
+	for _, key := range utils.SortedKeys(c.Nodes.List) {
+		key, node := key, c.Nodes.List[key]
+		result = append(result, reference{
+			path:  []string{"Nodes", "List", key, "RPC"},
+			value: &node.RPC,
+			// node is a copy of the map value
+			store: func() { c.Nodes.List[key] = node },
+		})
+		if node.OwnerPrivateKey != nil {
+			result = append(result, reference{path: []string{"Nodes", "List", key, "OwnerPrivateKey"}, value: node.OwnerPrivateKey})
+		}
+		if node.Fireblocks != nil {
+			result = append(result, reference{path: []string{"Nodes", "List", key, "Fireblocks", "ApiKey"}, value: &node.Fireblocks.ApiKey})
+		}
+	}
+
+	return result
+}
+
+// secretResolver returns resolver of the config secret references
+// enc: values are decrypted with the encryption settings available at the moment of resolution
+func (c *Config) secretResolver(baseDir string) *secrets.Resolver {
+	return newSecretResolver(baseDir, func() *utils.Encryptor {
+		if c.Utils.Encryption == nil || c.Utils.Encryption.Key == "" || c.Utils.Encryption.Salt == "" {
+			return nil
+		}
+
+		return c.Encryptor()
+	})
+}
+
+func newSecretResolver(baseDir string, encryptor func() *utils.Encryptor) *secrets.Resolver {
+	resolver := secrets.NewResolver()
+	resolver.Register("file", secrets.NewFileProvider(baseDir))
+	resolver.Register("keystore", secrets.NewKeystoreProvider(baseDir))
+	resolver.Register("vault", secrets.NewVaultProviderFromEnv())
+	resolver.Register(encryptedScheme, secrets.NewEncryptedProvider(encryptor))
+
+	return resolver
+}
+
+// resolveReferences replaces environment variables and secret references with their values
+// resolved values go to the runtime config, the source config keeps references
+func (c *Config) resolveReferences(resolver *secrets.Resolver, refs []reference) error {
+	var errs []error
+
+	for _, ref := range refs {
+		resolved, err := resolveValue(resolver, *ref.value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("resolve %s: %w", strings.Join(ref.path, "."), err))
+			continue
+		}
+
+		if resolved == *ref.value {
+			continue
+		}
+
+		*ref.value = resolved
+		if ref.store != nil {
+			ref.store()
+		}
+
+		if err := c.document.set(ref.path, resolved); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// resolveEncryptionReferences resolves Utils.Encryption key and salt, which can't be encrypted
+func (c *Config) resolveEncryptionReferences(resolver *secrets.Resolver) error {
+	for _, ref := range c.encryptionReferences() {
+		if strings.HasPrefix(*ref.value, encryptedScheme+":") {
+			return fmt.Errorf("%s can't be encrypted with itself", strings.Join(ref.path, "."))
+		}
+	}
+
+	return c.resolveReferences(resolver, c.encryptionReferences())
+}
+
+// resolveEncryptionValue resolves Utils.Encryption key or salt outside of the config
+func resolveEncryptionValue(resolver *secrets.Resolver, value string) (string, error) {
+	if strings.HasPrefix(value, encryptedScheme+":") {
+		return "", errors.New("key and salt can't be encrypted with themselves")
+	}
+
+	return resolveValue(resolver, value)
+}
+
+// resolveValue expands environment variables, then resolves the secret reference (file:, keystore:, vault:, enc:)
+func resolveValue(resolver *secrets.Resolver, value string) (string, error) {
+	var errs []error
+
+	expanded := variablePattern.ReplaceAllStringFunc(value, func(match string) string {
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+
+		parts := variablePattern.FindStringSubmatch(match)
+		if env, ok := os.LookupEnv(parts[1]); ok && (env != "" || parts[2] == "") {
+			return env
+		}
+
+		if parts[2] != "" {
+			return parts[3]
+		}
+
+		errs = append(errs, fmt.Errorf("environment variable %s is not set", parts[1]))
+		return match
+	})
+
+	if len(errs) > 0 {
+		return "", errors.Join(errs...)
+	}
+
+	return resolver.Resolve(context.Background(), expanded)
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"asterizm/builder/utils"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// parseTestConfig returns the config with the document, as ParseAndRefreshConfig does
+func parseTestConfig(t *testing.T, data string) *Config {
+	t.Helper()
+
+	root := &yaml.Node{}
+	if err := yaml.Unmarshal([]byte(data), root); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	cfg := &Config{}
+	if err := root.Decode(cfg); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	cfg.document = newDocument([]byte(data), root)
+
+	return cfg
+}
+
+func TestResolveReferencesUpdatesConfigAndDocument(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "sol-rpc"), []byte("https://sol.example.com\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_ETH_RPC", "https://eth.example.com")
+
+	cfg := parseTestConfig(t, `Nodes:
+  List:
+    ETH:
+      RPC: ${TEST_ETH_RPC}
+    SOL:
+      RPC: file:sol-rpc
+    TON:
+      RPC: https://ton.example.com
+`)
+
+	resolver := newSecretResolver(dir, func() *utils.Encryptor { return nil })
+	if err := cfg.resolveReferences(resolver, cfg.references()); err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+
+	data, err := cfg.Marshal()
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	runtime := &Config{}
+	if err := yaml.Unmarshal(data, runtime); err != nil {
+		t.Fatalf("unmarshal runtime config: %v", err)
+	}
+
+	expected := map[string]string{
+		"ETH": "https://eth.example.com",
+		"SOL": "https://sol.example.com",
+		"TON": "https://ton.example.com",
+	}
+
+	for network, rpc := range expected {
+		if got := cfg.Nodes.List[network].RPC; got != rpc {
+			t.Errorf("config %s RPC = %q, want %q", network, got, rpc)
+		}
+
+		if got := runtime.Nodes.List[network].RPC; got != rpc {
+			t.Errorf("runtime config %s RPC = %q, want %q", network, got, rpc)
+		}
+	}
+}
+
+func TestResolveReferencesReportsMissingVariable(t *testing.T) {
+	cfg := parseTestConfig(t, `Nodes:
+  List:
+    ETH:
+      RPC: ${TEST_MISSING_RPC}
+`)
+
+	resolver := newSecretResolver(t.TempDir(), func() *utils.Encryptor { return nil })
+	if err := cfg.resolveReferences(resolver, cfg.references()); err == nil {
+		t.Fatal("missing environment variable is not reported")
+	}
+
+	if got := cfg.Nodes.List["ETH"].RPC; got != "${TEST_MISSING_RPC}" {
+		t.Errorf("unresolved RPC is changed to %q", got)
+	}
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"asterizm/builder/keys"
+	"asterizm/builder/networks"
+	"asterizm/builder/utils"
+	"fmt"
+	"strings"
+)
+
+// Encryptor returns encryptor of the Utils.Encryption settings
+func (c *Config) Encryptor() *utils.Encryptor {
+	return c.Utils.Encryption.Encryptor()
+}
+
+// Encryptor returns encryptor of the encryption settings
+func (e *Encryption) Encryptor() *utils.Encryptor {
+	return utils.NewEncryptor(e.Key, e.Salt, e.CipherMethod).WithKdf(e.kdf())
+}
+
+func (e *Encryption) kdf() utils.Kdf {
+	if e.Kdf == nil {
+		return utils.Kdf{}
+	}
+
+	return utils.Kdf{
+		Algorithm:  e.Kdf.Algorithm,
+		Iterations: e.Kdf.Iterations,
+		Memory:     e.Kdf.Memory,
+		Threads:    e.Kdf.Threads,
+		N:          e.Kdf.N,
+		R:          e.Kdf.R,
+		P:          e.Kdf.P,
+	}
+}
+
+// EncryptOwnerKeys encrypts OwnerPrivateKey values with Utils.Encryption subkeys of the network
+// plaintext keys and keys encrypted without the network binding are encrypted, bound values are kept as is
+func (c *Config) EncryptOwnerKeys() error {
+	encryptor := c.Encryptor()
+
+	for _, key := range utils.SortedKeys(c.Nodes.List) {
+		node := c.Nodes.List[key]
+		if node.OwnerPrivateKey == nil || *node.OwnerPrivateKey == "" || encryptor.IsBoundTo(key, []byte(*node.OwnerPrivateKey)) {
+			continue
+		}
+
+		value, err := bindOwnerKey(encryptor, key, node)
+		if err != nil {
+			return fmt.Errorf("Nodes.List.%s.OwnerPrivateKey: %w", key, err)
+		}
+
+		node.OwnerPrivateKey = &value
+		c.Nodes.List[key] = node
+	}
+
+	return nil
+}
+
+// BindOwnerKeys re-encrypts owner keys which are not bound to their network, e.g. stored by an older builder
+func BindOwnerKeys(encryptor *utils.Encryptor, owners map[string]Node) error {
+	for _, network := range utils.SortedKeys(owners) {
+		owner := owners[network]
+		if owner.OwnerPrivateKey == nil || encryptor.IsBoundTo(network, []byte(*owner.OwnerPrivateKey)) {
+			continue
+		}
+
+		value, err := bindOwnerKey(encryptor, network, owner)
+		if err != nil {
+			return fmt.Errorf("stored %s owner key: %w", network, err)
+		}
+
+		owner.OwnerPrivateKey = &value
+		owners[network] = owner
+	}
+
+	return nil
+}
+
+// bindOwnerKey returns the owner key encrypted with the network subkeys
+// a value which can't be decrypted must be a plaintext private key
+func bindOwnerKey(encryptor *utils.Encryptor, network string, node Node) (string, error) {
+	plaintext, err := encryptor.Decrypt([]byte(*node.OwnerPrivateKey), "", "")
+	if err != nil {
+		if err := checkPlaintextOwnerKey(network, node); err != nil {
+			return "", err
+		}
+
+		plaintext = []byte(strings.TrimSpace(*node.OwnerPrivateKey))
+	}
+
+	encrypted, err := encryptor.EncryptFor(network, plaintext)
+	if err != nil {
+		return "", fmt.Errorf("encrypt: %w", err)
+	}
+
+	return string(encrypted), nil
+}
+
+// DecryptOwnerKey returns plaintext OwnerPrivateKey of the network
+func (c *Config) DecryptOwnerKey(network string) (string, error) {
+	node, ok := c.Nodes.List[network]
+	if !ok || node.OwnerPrivateKey == nil || *node.OwnerPrivateKey == "" {
+		return "", fmt.Errorf("please, fill Nodes.List.%s.OwnerPrivateKey", network)
+	}
+
+	plaintext, err := c.Encryptor().DecryptFor(network, []byte(*node.OwnerPrivateKey))
+	if err != nil {
+		// a value which can't be decrypted is plaintext only if it's a private key of the network
+		if utils.IsEnvelopeV2([]byte(*node.OwnerPrivateKey)) {
+			return "", fmt.Errorf("decrypt Nodes.List.%s.OwnerPrivateKey: %w", network, err)
+		}
+
+		if err := checkPlaintextOwnerKey(network, node); err != nil {
+			return "", fmt.Errorf("Nodes.List.%s.OwnerPrivateKey: %w", network, err)
+		}
+
+		return *node.OwnerPrivateKey, nil
+	}
+
+	return string(plaintext), nil
+}
+
+// ConsoleOwners returns owners with keys encrypted for the console container
+// the console decrypts the legacy format with Utils.Encryption key and salt, it doesn't read v2 envelopes and network subkeys
+func ConsoleOwners(encryptor *utils.Encryptor, owners map[string]Node) (map[string]Node, error) {
+	result := make(map[string]Node, len(owners))
+
+	for _, network := range utils.SortedKeys(owners) {
+		owner := owners[network]
+		if owner.OwnerPrivateKey == nil {
+			continue
+		}
+
+		plaintext, err := encryptor.DecryptFor(network, []byte(*owner.OwnerPrivateKey))
+		if err != nil {
+			return nil, fmt.Errorf("decrypt %s owner key: %w", network, err)
+		}
+
+		encrypted, err := encryptor.EncryptLegacy(plaintext, "", "")
+		if err != nil {
+			return nil, fmt.Errorf("encrypt %s owner key: %w", network, err)
+		}
+
+		value := string(encrypted)
+		owner.OwnerPrivateKey = &value
+		result[network] = owner
+	}
+
+	return result, nil
+}
+
+// checkPlaintextOwnerKey makes sure that a value which can't be decrypted is a private key,
+// not a value encrypted with another Utils.Encryption settings
+func checkPlaintextOwnerKey(key string, node Node) error {
+	network, ok := networks.Lookup(key)
+	if !ok {
+		return nil
+	}
+
+	walletType := ""
+	if node.OwnerWalletType != nil {
+		walletType = *node.OwnerWalletType
+	}
+
+	if _, err := keys.Derive(network, *node.OwnerPrivateKey, walletType, false); err != nil {
+		return fmt.Errorf("neither a private key (%v) nor encrypted for %s with Utils.Encryption settings", err, key)
+	}
+
+	return nil
+}
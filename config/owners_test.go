@@ -0,0 +1,146 @@
+package config
+
+import (
+	"asterizm/builder/utils"
+	"strings"
+	"testing"
+)
+
+const testOwnerKey = "0x4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+
+func ownerKeyConfig(keys map[string]string) *Config {
+	cfg := &Config{}
+	cfg.Utils.Encryption = &Encryption{Key: "test-encryption-key", Salt: "test-encryption-salt", CipherMethod: DefaultCipherMethod}
+	cfg.Nodes.List = make(map[string]Node)
+
+	for network, key := range keys {
+		key := key
+		cfg.Nodes.List[network] = Node{OwnerPrivateKey: &key}
+	}
+
+	return cfg
+}
+
+func TestEncryptOwnerKeysBindsToNetwork(t *testing.T) {
+	encryptor := ownerKeyConfig(nil).Encryptor()
+
+	legacy, err := encryptor.EncryptLegacy([]byte(testOwnerKey), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unbound, err := encryptor.Encrypt([]byte(testOwnerKey), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bound, err := encryptor.EncryptFor("ARB", []byte(testOwnerKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := ownerKeyConfig(map[string]string{
+		"ETH": testOwnerKey,
+		"BSC": string(legacy),
+		"POL": string(unbound),
+		"ARB": string(bound),
+	})
+
+	if err := cfg.EncryptOwnerKeys(); err != nil {
+		t.Fatalf("encrypt owner keys: %v", err)
+	}
+
+	for _, network := range []string{"ETH", "BSC", "POL", "ARB"} {
+		value := []byte(*cfg.Nodes.List[network].OwnerPrivateKey)
+		if !encryptor.IsBoundTo(network, value) {
+			t.Errorf("%s owner key isn't bound to the network", network)
+		}
+
+		plaintext, err := cfg.DecryptOwnerKey(network)
+		if err != nil || plaintext != testOwnerKey {
+			t.Errorf("%s owner key is %q, %v", network, plaintext, err)
+		}
+	}
+
+	// bound values are kept as is
+	if *cfg.Nodes.List["ARB"].OwnerPrivateKey != string(bound) {
+		t.Error("bound ARB owner key is re-encrypted")
+	}
+}
+
+func TestEncryptOwnerKeysRejectsAnotherNetworkValue(t *testing.T) {
+	encryptor := ownerKeyConfig(nil).Encryptor()
+
+	ethValue, err := encryptor.EncryptFor("ETH", []byte(testOwnerKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := ownerKeyConfig(map[string]string{"BSC": string(ethValue)})
+	if err := cfg.EncryptOwnerKeys(); err == nil || !strings.Contains(err.Error(), "Nodes.List.BSC.OwnerPrivateKey") {
+		t.Errorf("error %v", err)
+	}
+}
+
+func TestBindOwnerKeys(t *testing.T) {
+	encryptor := ownerKeyConfig(nil).Encryptor()
+
+	unbound, err := encryptor.Encrypt([]byte(testOwnerKey), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value := string(unbound)
+	owners := map[string]Node{"ETH": {OwnerPrivateKey: &value}}
+	if err := BindOwnerKeys(encryptor, owners); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+
+	bound := []byte(*owners["ETH"].OwnerPrivateKey)
+	if !encryptor.IsBoundTo("ETH", bound) {
+		t.Error("stored owner key isn't bound")
+	}
+
+	// the console gets the legacy format without the binding
+	console, err := ConsoleOwners(encryptor, owners)
+	if err != nil {
+		t.Fatalf("console owners: %v", err)
+	}
+
+	consoleValue := []byte(*console["ETH"].OwnerPrivateKey)
+	if utils.IsEnvelopeV2(consoleValue) {
+		t.Error("console owner key is a v2 envelope")
+	}
+
+	if plaintext, err := encryptor.Decrypt(consoleValue, "", ""); err != nil || string(plaintext) != testOwnerKey {
+		t.Errorf("console owner key is %q, %v", plaintext, err)
+	}
+}
+
+func TestDecryptOwnerKeyRejectsForeignCiphertext(t *testing.T) {
+	other := utils.NewEncryptor("another-encryption-key", "another-encryption-salt", DefaultCipherMethod)
+
+	bound, err := other.EncryptFor("ETH", []byte(testOwnerKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	legacy, err := other.EncryptLegacy([]byte(testOwnerKey), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := ownerKeyConfig(map[string]string{"ETH": string(bound), "BSC": string(legacy), "POL": testOwnerKey})
+
+	for _, network := range []string{"ETH", "BSC"} {
+		plaintext, err := cfg.DecryptOwnerKey(network)
+		if err == nil || !strings.Contains(err.Error(), "Nodes.List."+network+".OwnerPrivateKey") {
+			t.Errorf("%s owner key is %q, %v", network, plaintext, err)
+		}
+	}
+
+	// a value which isn't encrypted is a plaintext key
+	if plaintext, err := cfg.DecryptOwnerKey("POL"); err != nil || plaintext != testOwnerKey {
+		t.Errorf("POL owner key is %q, %v", plaintext, err)
+	}
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"asterizm/builder/utils"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+)
+
+// OwnersName is the encrypted store of owner credentials removed from the runtime config
+const OwnersName = "owners.enc"
+
+type storedOwner struct {
+	OwnerPrivateKey string  `yaml:"OwnerPrivateKey"`
+	OwnerPublicKey  *string `yaml:"OwnerPublicKey,omitempty"`
+	OwnerWalletType *string `yaml:"OwnerWalletType,omitempty"`
+}
+
+// LoadOwners returns owner credentials saved by the previous runs, owner keys stay encrypted
+// the store is decrypted with the encryptor of the config, an absent store is empty
+func LoadOwners(configFile string, encryptor *utils.Encryptor) (map[string]Node, error) {
+	name := StatePath(configFile, OwnersName)
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return map[string]Node{}, nil
+		}
+
+		return nil, fmt.Errorf("error reading owners store: %w", err)
+	}
+
+	plaintext, err := encryptor.Decrypt(data, "", "")
+	if err != nil {
+		return nil, fmt.Errorf("decrypt owners store %s (is it encrypted with other Utils.Encryption settings?): %w", name, err)
+	}
+
+	stored := make(map[string]storedOwner)
+	if err := yaml.Unmarshal(plaintext, &stored); err != nil {
+		return nil, fmt.Errorf("error unmarshaling owners store: %w", err)
+	}
+
+	owners := make(map[string]Node, len(stored))
+	for network, owner := range stored {
+		privateKey := owner.OwnerPrivateKey
+		owners[network] = Node{
+			OwnerPrivateKey: &privateKey,
+			OwnerPublicKey:  owner.OwnerPublicKey,
+			OwnerWalletType: owner.OwnerWalletType,
+		}
+	}
+
+	return owners, nil
+}
+
+// SaveOwners encrypts and writes owner credentials readable only by the file owner
+func SaveOwners(configFile string, encryptor *utils.Encryptor, owners map[string]Node) error {
+	stored := make(map[string]storedOwner, len(owners))
+	for network, owner := range owners {
+		if owner.OwnerPrivateKey == nil {
+			continue
+		}
+
+		stored[network] = storedOwner{
+			OwnerPrivateKey: *owner.OwnerPrivateKey,
+			OwnerPublicKey:  owner.OwnerPublicKey,
+			OwnerWalletType: owner.OwnerWalletType,
+		}
+	}
+
+	plaintext, err := yaml.Marshal(stored)
+	if err != nil {
+		return fmt.Errorf("error marshaling owners store: %w", err)
+	}
+
+	encrypted, err := encryptor.Encrypt(plaintext, "", "")
+	if err != nil {
+		return fmt.Errorf("encrypt owners store: %w", err)
+	}
+
+	name := StatePath(configFile, OwnersName)
+	if err := utils.WriteFileAtomic(name, encrypted, 0600); err != nil {
+		return fmt.Errorf("write owners store: %w", err)
+	}
+
+	// WriteFileAtomic keeps the mode of an existing file
+	return os.Chmod(name, 0600)
+}
+
+// MergeOwners returns stored owners updated with the owners of the config
+func MergeOwners(stored, owners map[string]Node) map[string]Node {
+	result := make(map[string]Node, len(stored)+len(owners))
+	for network, owner := range stored {
+		result[network] = owner
+	}
+
+	for network, owner := range owners {
+		result[network] = owner
+	}
+
+	return result
+}
+
+// reencryptOwners re-encrypts stored owner keys with the new encryption settings and network subkeys
+func reencryptOwners(owners map[string]Node, oldEncryptor, newEncryptor *utils.Encryptor) error {
+	var errs []error
+
+	for _, network := range utils.SortedKeys(owners) {
+		owner := owners[network]
+
+		plaintext, err := oldEncryptor.DecryptFor(network, []byte(*owner.OwnerPrivateKey))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("decrypt stored %s owner key: %w", network, err))
+			continue
+		}
+
+		encrypted, err := newEncryptor.EncryptFor(network, plaintext)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("encrypt stored %s owner key: %w", network, err))
+			continue
+		}
+
+		value := string(encrypted)
+		owner.OwnerPrivateKey = &value
+		owners[network] = owner
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,164 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// ownerStoreConfig returns a config path with an existing state directory
+func ownerStoreConfig(t *testing.T) string {
+	t.Helper()
+
+	configFile := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.MkdirAll(StateDir(configFile), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	return configFile
+}
+
+func TestOwnerStoreRoundTrip(t *testing.T) {
+	configFile := ownerStoreConfig(t)
+	encryptor := ownerKeyConfig(nil).Encryptor()
+
+	owners, err := LoadOwners(configFile, encryptor)
+	if err != nil || len(owners) != 0 {
+		t.Fatalf("absent store is %v, %v", owners, err)
+	}
+
+	ethKey, tonKey, publicKey, walletType := "eth-encrypted-key", "ton-encrypted-key", "ton-public-key", "v4r2"
+	err = SaveOwners(configFile, encryptor, map[string]Node{
+		"ETH": {OwnerPrivateKey: &ethKey},
+		"TON": {OwnerPrivateKey: &tonKey, OwnerPublicKey: &publicKey, OwnerWalletType: &walletType},
+		// nodes without a key aren't stored
+		"BSC": {OwnerPublicKey: &publicKey},
+	})
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	// the store is encrypted
+	data, err := os.ReadFile(StatePath(configFile, OwnersName))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(data), ethKey) || strings.Contains(string(data), "OwnerPrivateKey") {
+		t.Error("owners store is plaintext")
+	}
+
+	owners, err = LoadOwners(configFile, encryptor)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	if len(owners) != 2 {
+		t.Fatalf("owners %v", owners)
+	}
+
+	if eth := owners["ETH"]; *eth.OwnerPrivateKey != ethKey || eth.OwnerPublicKey != nil || eth.OwnerWalletType != nil {
+		t.Errorf("ETH owner %+v", eth)
+	}
+
+	if ton := owners["TON"]; *ton.OwnerPrivateKey != tonKey || *ton.OwnerPublicKey != publicKey || *ton.OwnerWalletType != walletType {
+		t.Errorf("TON owner %+v", ton)
+	}
+}
+
+func TestSaveOwnersMode(t *testing.T) {
+	configFile := ownerStoreConfig(t)
+	name := StatePath(configFile, OwnersName)
+
+	// the store of an older builder is world-readable
+	if err := os.WriteFile(name, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	key := "eth-encrypted-key"
+	if err := SaveOwners(configFile, ownerKeyConfig(nil).Encryptor(), map[string]Node{"ETH": {OwnerPrivateKey: &key}}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	info, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode %o, want 600", info.Mode().Perm())
+	}
+}
+
+func TestLoadOwnersWrongKey(t *testing.T) {
+	configFile := ownerStoreConfig(t)
+
+	key := "eth-encrypted-key"
+	if err := SaveOwners(configFile, ownerKeyConfig(nil).Encryptor(), map[string]Node{"ETH": {OwnerPrivateKey: &key}}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	other := (&Encryption{Key: "another-encryption-key", Salt: "test-encryption-salt", CipherMethod: DefaultCipherMethod}).Encryptor()
+	if _, err := LoadOwners(configFile, other); err == nil || !strings.Contains(err.Error(), "other Utils.Encryption settings") {
+		t.Errorf("error %v", err)
+	}
+}
+
+func TestMergeOwners(t *testing.T) {
+	storedEth, storedBsc, configEth := "stored-eth", "stored-bsc", "config-eth"
+	stored := map[string]Node{"ETH": {OwnerPrivateKey: &storedEth}, "BSC": {OwnerPrivateKey: &storedBsc}}
+	owners := map[string]Node{"ETH": {OwnerPrivateKey: &configEth}}
+
+	merged := MergeOwners(stored, owners)
+	if len(merged) != 2 || *merged["ETH"].OwnerPrivateKey != configEth || *merged["BSC"].OwnerPrivateKey != storedBsc {
+		t.Errorf("merged %v", merged)
+	}
+
+	// the arguments are kept as is
+	if *stored["ETH"].OwnerPrivateKey != storedEth || len(owners) != 1 {
+		t.Error("arguments are modified")
+	}
+}
+
+func TestReencryptOwners(t *testing.T) {
+	oldEncryptor := ownerKeyConfig(nil).Encryptor()
+	newEncryptor := (&Encryption{Key: "new-encryption-key", Salt: "new-encryption-salt", CipherMethod: DefaultCipherMethod}).Encryptor()
+
+	bound, err := oldEncryptor.EncryptFor("ETH", []byte(testOwnerKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// stored by older builders
+	unbound, err := oldEncryptor.Encrypt([]byte(testOwnerKey), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ethKey, bscKey := string(bound), string(unbound)
+	owners := map[string]Node{"ETH": {OwnerPrivateKey: &ethKey}, "BSC": {OwnerPrivateKey: &bscKey}}
+	if err := reencryptOwners(owners, oldEncryptor, newEncryptor); err != nil {
+		t.Fatalf("re-encrypt: %v", err)
+	}
+
+	for network, owner := range owners {
+		value := []byte(*owner.OwnerPrivateKey)
+		if !newEncryptor.IsBoundTo(network, value) {
+			t.Errorf("%s owner key isn't bound with the new key", network)
+		}
+
+		if plaintext, err := newEncryptor.DecryptFor(network, value); err != nil || string(plaintext) != testOwnerKey {
+			t.Errorf("%s owner key is %q, %v", network, plaintext, err)
+		}
+
+		if oldEncryptor.IsEncryptedFor(network, value) {
+			t.Errorf("%s owner key decrypts with the old key", network)
+		}
+	}
+
+	// a value of another key is reported with its network
+	if err := reencryptOwners(map[string]Node{"POL": {OwnerPrivateKey: &ethKey}}, newEncryptor, oldEncryptor); err == nil || !strings.Contains(err.Error(), "POL") {
+		t.Errorf("error %v", err)
+	}
+}
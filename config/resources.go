@@ -0,0 +1,251 @@
+package config
+
+import (
+	"asterizm/builder/networks"
+	"asterizm/builder/utils"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultRestart is the restart policy of the services without restart settings
+const DefaultRestart = "always"
+
+var (
+	restartPolicies = []string{"no", "always", "on-failure", "unless-stopped"}
+	memoryPattern   = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?[bkmgBKMG]?$`)
+)
+
+// Resources are limits and restart settings of a generated service, empty fields are not limited
+type Resources struct {
+	// cpu cores, e.g. "0.5"
+	Cpus string `yaml:"Cpus,omitempty"`
+	// memory with b/k/m/g suffix, e.g. "512m"
+	Memory            string `yaml:"Memory,omitempty"`
+	MemoryReservation string `yaml:"MemoryReservation,omitempty"`
+	Pids              int64  `yaml:"Pids,omitempty"`
+	// open files ulimit
+	Nofile          uint64 `yaml:"Nofile,omitempty"`
+	StopGracePeriod string `yaml:"StopGracePeriod,omitempty"`
+
+	// no, always, on-failure or unless-stopped
+	Restart string `yaml:"Restart,omitempty"`
+	// on-failure only
+	MaxRestarts uint64 `yaml:"MaxRestarts,omitempty"`
+}
+
+// ServiceResources are Deployment.Resources sections
+type ServiceResources struct {
+	// console, cron and scanners
+	Default  *Resources `yaml:"Default,omitempty"`
+	Console  *Resources `yaml:"Console,omitempty"`
+	Cron     *Resources `yaml:"Cron,omitempty"`
+	Postgres *Resources `yaml:"Postgres,omitempty"`
+	// scanners of the networks, override Default
+	Networks map[string]*Resources `yaml:"Networks,omitempty"`
+}
+
+// merge returns the settings overridden by non-empty fields of the other settings
+func (r Resources) merge(other *Resources) Resources {
+	if other == nil {
+		return r
+	}
+
+	for _, field := range []struct {
+		value    *string
+		override string
+	}{
+		{&r.Cpus, other.Cpus},
+		{&r.Memory, other.Memory},
+		{&r.MemoryReservation, other.MemoryReservation},
+		{&r.StopGracePeriod, other.StopGracePeriod},
+		{&r.Restart, other.Restart},
+	} {
+		if field.override != "" {
+			*field.value = field.override
+		}
+	}
+
+	if other.Pids != 0 {
+		r.Pids = other.Pids
+	}
+
+	if other.Nofile != 0 {
+		r.Nofile = other.Nofile
+	}
+
+	if other.MaxRestarts != 0 {
+		r.MaxRestarts = other.MaxRestarts
+	}
+
+	return r
+}
+
+// ConsoleResources returns resources of the console service
+func (c *Config) ConsoleResources() Resources {
+	resources := c.Deployment.Resources
+	return Resources{}.merge(resources.Default).merge(resources.Console)
+}
+
+// CronResources returns resources of the cron service
+func (c *Config) CronResources() Resources {
+	resources := c.Deployment.Resources
+	return Resources{}.merge(resources.Default).merge(resources.Cron)
+}
+
+// PostgresResources returns resources of the database service, Default isn't applied to it
+func (c *Config) PostgresResources() Resources {
+	return Resources{}.merge(c.Deployment.Resources.Postgres)
+}
+
+// ScannerResources returns resources of the network scanner
+func (c *Config) ScannerResources(network string) Resources {
+	resources := c.Deployment.Resources
+	return Resources{}.merge(resources.Default).merge(resources.network(network))
+}
+
+// network returns the section of the network scanner, keys which differ only by case are rejected by validation
+func (s ServiceResources) network(network string) *Resources {
+	for _, key := range utils.SortedKeys(s.Networks) {
+		if canonicalNetwork(key) == network {
+			return s.Networks[key]
+		}
+	}
+
+	return nil
+}
+
+// RestartPolicy returns the restart policy, DefaultRestart if absent
+func (r Resources) RestartPolicy() string {
+	if r.Restart == "" {
+		return DefaultRestart
+	}
+
+	return r.Restart
+}
+
+// validateValues checks formats of the section values
+func (r Resources) validateValues(path string) error {
+	var errs []error
+
+	if r.Cpus != "" {
+		if cpus, err := strconv.ParseFloat(r.Cpus, 64); err != nil || cpus <= 0 {
+			errs = append(errs, fmt.Errorf("%s.Cpus: bad value %q, use e.g. \"0.5\"", path, r.Cpus))
+		}
+	}
+
+	for _, field := range []struct {
+		name  string
+		value string
+	}{
+		{name: "Memory", value: r.Memory},
+		{name: "MemoryReservation", value: r.MemoryReservation},
+	} {
+		if field.value != "" && !memoryPattern.MatchString(field.value) {
+			errs = append(errs, fmt.Errorf("%s.%s: bad value %q, use e.g. 512m or 1g", path, field.name, field.value))
+		}
+	}
+
+	if r.Pids < 0 {
+		errs = append(errs, fmt.Errorf("%s.Pids: must be positive", path))
+	}
+
+	if r.StopGracePeriod != "" {
+		if duration, err := time.ParseDuration(r.StopGracePeriod); err != nil || duration <= 0 {
+			errs = append(errs, fmt.Errorf("%s.StopGracePeriod: bad duration %q, use e.g. 10s", path, r.StopGracePeriod))
+		}
+	}
+
+	if r.Restart != "" && !utils.InSlice(r.Restart, restartPolicies) {
+		errs = append(errs, fmt.Errorf("%s.Restart: must be one of %s", path, strings.Join(restartPolicies, "/")))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateRestart checks that the restart settings of the service are compatible
+func (r Resources) validateRestart(service string) error {
+	if r.MaxRestarts != 0 && r.RestartPolicy() != "on-failure" {
+		return fmt.Errorf("Deployment.Resources: MaxRestarts of %s is applicable to on-failure restart only", service)
+	}
+
+	return nil
+}
+
+// validateResources checks resources of every generated service
+func (c *Config) validateResources() error {
+	resources := c.Deployment.Resources
+	path := "Deployment.Resources"
+
+	var errs []error
+	for _, section := range []struct {
+		name      string
+		resources *Resources
+	}{
+		{name: "Default", resources: resources.Default},
+		{name: "Console", resources: resources.Console},
+		{name: "Cron", resources: resources.Cron},
+		{name: "Postgres", resources: resources.Postgres},
+	} {
+		if section.resources == nil {
+			continue
+		}
+
+		if err := section.resources.validateValues(path + "." + section.name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	sources := make(map[string]string, len(resources.Networks))
+	for _, key := range utils.SortedKeys(resources.Networks) {
+		canonical := canonicalNetwork(key)
+		if source, ok := sources[canonical]; ok {
+			errs = append(errs, fmt.Errorf("duplicate network %s.Networks.%s: %q and %q", path, canonical, source, key))
+			continue
+		}
+		sources[canonical] = key
+
+		if _, ok := c.Nodes.List[canonical]; !ok {
+			errs = append(errs, fmt.Errorf("%s.Networks.%s: network is absent in Nodes.List", path, key))
+			continue
+		}
+
+		if resources.Networks[key] == nil {
+			continue
+		}
+
+		if err := resources.Networks[key].validateValues(path + ".Networks." + key); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// restart settings are checked after merge, they may come from different sections
+	services := map[string]Resources{
+		"console":  c.ConsoleResources(),
+		"cron":     c.CronResources(),
+		"postgres": c.PostgresResources(),
+	}
+	for _, network := range utils.SortedKeys(c.Nodes.List) {
+		services[network+" scanner"] = c.ScannerResources(network)
+	}
+
+	for _, service := range utils.SortedKeys(services) {
+		if err := services[service].validateRestart(service); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// canonicalNetwork returns the registry code of the network key
+func canonicalNetwork(key string) string {
+	if network, ok := networks.Lookup(key); ok {
+		return network.Code
+	}
+
+	return strings.ToUpper(key)
+}
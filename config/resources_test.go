@@ -0,0 +1,56 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateResourcesRejectsDuplicateNetworks(t *testing.T) {
+	cfg := parseTestConfig(t, `Nodes:
+  List:
+    ETH:
+      RPC: https://eth.example.com
+Deployment:
+  Resources:
+    Networks:
+      ETH:
+        Memory: 512m
+      eth:
+        Memory: 1g
+`)
+
+	err := cfg.validateResources()
+	if err == nil || !strings.Contains(err.Error(), `duplicate network Deployment.Resources.Networks.ETH: "ETH" and "eth"`) {
+		t.Errorf("error %v", err)
+	}
+}
+
+func TestScannerResources(t *testing.T) {
+	cfg := parseTestConfig(t, `Nodes:
+  List:
+    ETH:
+      RPC: https://eth.example.com
+    SOL:
+      RPC: https://sol.example.com
+Deployment:
+  Resources:
+    Default:
+      Cpus: "0.5"
+      Memory: 256m
+    Networks:
+      eth:
+        Memory: 1g
+`)
+
+	if err := cfg.validateResources(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	if resources := cfg.ScannerResources("ETH"); resources.Cpus != "0.5" || resources.Memory != "1g" {
+		t.Errorf("ETH scanner resources %+v", resources)
+	}
+
+	if resources := cfg.ScannerResources("SOL"); resources.Cpus != "0.5" || resources.Memory != "256m" {
+		t.Errorf("SOL scanner resources %+v", resources)
+	}
+}
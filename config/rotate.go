@@ -0,0 +1,200 @@
+package config
+
+import (
+	"asterizm/builder/utils"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"strings"
+)
+
+// Rotation is a result of the encryption key rotation
+type Rotation struct {
+	// config with the new encryption settings and owner keys encrypted with them
+	Config *Config
+	// updated source config, nil if the source config doesn't keep rotated values
+	Source []byte
+	// rotated fields, source config fields are marked with "(source)"
+	Fields []string
+	// owners store re-encrypted with the new encryption settings
+	Owners map[string]Node
+	// secrets of the config before rotation, the old key and owner keys encrypted with it
+	Secrets []string
+}
+
+// RotateEncryption generates new Utils.Encryption key and salt and re-encrypts owner keys with them
+// the key and salt defined in the source config are replaced there, generated ones only in the runtime config
+func RotateEncryption(dockerDbHost, configFile string, lenient bool) (*Rotation, error) {
+	config, err := ParseAndRefreshConfig(dockerDbHost, configFile, lenient)
+	if err != nil {
+		return nil, err
+	}
+
+	// raw source values, before references resolution and generation
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+
+	root := &yaml.Node{}
+	if err := yaml.Unmarshal(data, root); err != nil {
+		return nil, fmt.Errorf("error unmarshaling yaml: %w", err)
+	}
+
+	source := &Config{}
+	if err := root.Decode(source); err != nil {
+		return nil, fmt.Errorf("error unmarshaling yaml: %w", err)
+	}
+
+	sourceDocument := newDocument(data, root)
+	if source.Utils.Encryption == nil {
+		source.Utils.Encryption = &Encryption{}
+	}
+
+	key, err := utils.GenerateEncryptionString(48)
+	if err != nil {
+		return nil, fmt.Errorf("generate encryption key: %w", err)
+	}
+
+	salt, err := utils.GenerateEncryptionString(48)
+	if err != nil {
+		return nil, fmt.Errorf("generate encryption salt: %w", err)
+	}
+
+	oldEncryptor := config.Encryptor()
+	encryption := *config.Utils.Encryption
+	encryption.Key, encryption.Salt = key, salt
+	newEncryptor := encryption.Encryptor()
+
+	var (
+		rotation      = &Rotation{Config: config, Secrets: config.Secrets()}
+		sourceChanged bool
+		errs          []error
+	)
+
+	for _, field := range []struct {
+		name      string
+		raw       string
+		resolved  string
+		generated string
+	}{
+		{name: "Key", raw: source.Utils.Encryption.Key, resolved: config.Utils.Encryption.Key, generated: key},
+		{name: "Salt", raw: source.Utils.Encryption.Salt, resolved: config.Utils.Encryption.Salt, generated: salt},
+	} {
+		path := []string{"Utils", "Encryption", field.name}
+		if field.raw != "" && field.raw != field.resolved {
+			errs = append(errs, fmt.Errorf("%s is a reference, rotate the referenced secret manually", strings.Join(path, ".")))
+			continue
+		}
+
+		if err := config.document.set(path, field.generated); err != nil {
+			return nil, err
+		}
+
+		name := strings.Join(path, ".")
+		if field.raw != "" {
+			if err := sourceDocument.set(path, field.generated); err != nil {
+				return nil, err
+			}
+			sourceChanged = true
+			name += " (source)"
+		}
+
+		rotation.Fields = append(rotation.Fields, name)
+	}
+
+	for _, rawKey := range utils.SortedKeys(source.Nodes.List) {
+		network := strings.ToUpper(rawKey)
+		raw := source.Nodes.List[rawKey].OwnerPrivateKey
+		node := config.Nodes.List[network]
+		if raw == nil || node.OwnerPrivateKey == nil || *node.OwnerPrivateKey == "" {
+			continue
+		}
+
+		// ciphertext of another key is reported, only plaintext private keys are taken as is
+		isEncrypted := oldEncryptor.IsEncryptedFor(network, []byte(*node.OwnerPrivateKey))
+		plaintext, err := config.DecryptOwnerKey(network)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		encrypted, err := newEncryptor.EncryptFor(network, []byte(strings.TrimSpace(plaintext)))
+		if err != nil {
+			return nil, fmt.Errorf("encrypt Nodes.List.%s.OwnerPrivateKey: %w", network, err)
+		}
+
+		// plaintext source values are encrypted while deploy, there is nothing to rotate in the source
+		if isEncrypted {
+			if *raw != *node.OwnerPrivateKey {
+				errs = append(errs, fmt.Errorf("Nodes.List.%s.OwnerPrivateKey is a reference to an encrypted value, re-encrypt it manually", rawKey))
+				continue
+			}
+
+			if err := sourceDocument.set([]string{"Nodes", "List", rawKey, "OwnerPrivateKey"}, string(encrypted)); err != nil {
+				return nil, err
+			}
+			sourceChanged = true
+			rotation.Fields = append(rotation.Fields, fmt.Sprintf("Nodes.List.%s.OwnerPrivateKey (source)", rawKey))
+		}
+
+		value := string(encrypted)
+		node.OwnerPrivateKey = &value
+		config.Nodes.List[network] = node
+	}
+
+	// enc: references of the source config are re-encrypted in place
+	for _, ref := range source.references() {
+		encrypted, ok := strings.CutPrefix(*ref.value, encryptedScheme+":")
+		if !ok {
+			continue
+		}
+
+		name := strings.Join(ref.path, ".")
+		plaintext, err := oldEncryptor.Decrypt([]byte(encrypted), "", "")
+		if err != nil {
+			errs = append(errs, fmt.Errorf("decrypt %s: %w", name, err))
+			continue
+		}
+
+		reencrypted, err := newEncryptor.Encrypt(plaintext, "", "")
+		if err != nil {
+			return nil, fmt.Errorf("encrypt %s: %w", name, err)
+		}
+
+		if err := sourceDocument.set(ref.path, encryptedScheme+":"+string(reencrypted)); err != nil {
+			return nil, err
+		}
+		sourceChanged = true
+		rotation.Fields = append(rotation.Fields, name+" (source)")
+	}
+
+	// the owners store is encrypted with the old settings as well
+	if rotation.Owners, err = LoadOwners(configFile, oldEncryptor); err != nil {
+		errs = append(errs, err)
+	} else {
+		for _, owner := range rotation.Owners {
+			rotation.Secrets = append(rotation.Secrets, *owner.OwnerPrivateKey)
+		}
+
+		if err := reencryptOwners(rotation.Owners, oldEncryptor, newEncryptor); err != nil {
+			errs = append(errs, err)
+		} else if len(rotation.Owners) > 0 {
+			rotation.Fields = append(rotation.Fields, StateDirName+"/"+OwnersName)
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	config.Utils.Encryption = &encryption
+	if sourceChanged {
+		if rotation.Source, err = sourceDocument.render(); err != nil {
+			return nil, err
+		}
+	}
+
+	return rotation, nil
+}
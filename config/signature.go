@@ -0,0 +1,114 @@
+package config
+
+import (
+	"asterizm/builder/utils"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	// SignatureName is the signature of the runtime config, it isn't mounted into the containers
+	SignatureName = RuntimeConfigName + ".sig"
+	// SigningKeyName is the random key of the signature, it isn't mounted into the containers
+	// Utils.Encryption isn't used, the containers can read it from the runtime config
+	SigningKeyName = "signing.key"
+
+	signingSalt = "asterizm runtime config signature"
+)
+
+var (
+	// ErrNotSigned is returned if the runtime config has no signature or signing key, e.g. it is deployed by an older builder
+	ErrNotSigned = errors.New("runtime config is not signed")
+	// ErrConfigChanged is returned if the runtime config doesn't match its signature
+	ErrConfigChanged = errors.New("runtime config was changed outside the builder")
+)
+
+// SignRuntimeConfig writes the signature of the runtime config data next to it, the signing key is generated once
+func SignRuntimeConfig(configFile string, data []byte) error {
+	signer, err := signingEncryptor(configFile, true)
+	if err != nil {
+		return err
+	}
+
+	signature, err := signer.Sign(data)
+	if err != nil {
+		return fmt.Errorf("sign runtime config: %w", err)
+	}
+
+	if err := utils.WriteFileAtomic(StatePath(configFile, SignatureName), []byte(signature+"\n"), 0644); err != nil {
+		return fmt.Errorf("write runtime config signature: %w", err)
+	}
+
+	return nil
+}
+
+// VerifyRuntimeConfig checks the deployed runtime config against its signature
+func VerifyRuntimeConfig(configFile string) error {
+	data, err := os.ReadFile(StatePath(configFile, RuntimeConfigName))
+	if err != nil {
+		return fmt.Errorf("error reading runtime config: %w", err)
+	}
+
+	signature, err := os.ReadFile(StatePath(configFile, SignatureName))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ErrNotSigned
+		}
+
+		return fmt.Errorf("error reading runtime config signature: %w", err)
+	}
+
+	signer, err := signingEncryptor(configFile, false)
+	if err != nil {
+		return err
+	}
+
+	if err := signer.Verify(data, string(signature)); err != nil {
+		if errors.Is(err, utils.ErrSignatureMismatch) {
+			return ErrConfigChanged
+		}
+
+		return fmt.Errorf("verify runtime config: %w", err)
+	}
+
+	return nil
+}
+
+// signingEncryptor returns encryptor of the signing key, an absent key is generated if create is set
+func signingEncryptor(configFile string, create bool) (*utils.Encryptor, error) {
+	name := StatePath(configFile, SigningKeyName)
+
+	data, err := os.ReadFile(name)
+	if errors.Is(err, os.ErrNotExist) {
+		if !create {
+			return nil, ErrNotSigned
+		}
+
+		key, err := utils.GenerateEncryptionString(48)
+		if err != nil {
+			return nil, fmt.Errorf("generate signing key: %w", err)
+		}
+
+		if err := utils.WriteFileAtomic(name, []byte(key+"\n"), 0600); err != nil {
+			return nil, fmt.Errorf("write signing key: %w", err)
+		}
+
+		// WriteFileAtomic keeps the mode of an existing file
+		if err := os.Chmod(name, 0600); err != nil {
+			return nil, fmt.Errorf("write signing key: %w", err)
+		}
+
+		data = []byte(key)
+	} else if err != nil {
+		return nil, fmt.Errorf("error reading signing key: %w", err)
+	}
+
+	key := strings.TrimSpace(string(data))
+	if key == "" {
+		return nil, fmt.Errorf("signing key %s is empty", name)
+	}
+
+	return utils.NewEncryptor(key, signingSalt, DefaultCipherMethod), nil
+}
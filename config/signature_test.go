@@ -0,0 +1,122 @@
+package config
+
+import (
+	"asterizm/builder/utils"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testRuntimeConfig = `Utils:
+  Encryption:
+    Key: test-encryption-key
+    Salt: test-encryption-salt
+Nodes:
+  List:
+    ETH:
+      RPC: https://eth.example.com
+`
+
+// deployTestConfig writes the runtime config to the state dir and returns the source config path
+func deployTestConfig(t *testing.T, data string) string {
+	t.Helper()
+
+	configFile := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.MkdirAll(StateDir(configFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(StatePath(configFile, RuntimeConfigName), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return configFile
+}
+
+func TestVerifyRuntimeConfig(t *testing.T) {
+	configFile := deployTestConfig(t, testRuntimeConfig)
+
+	if err := SignRuntimeConfig(configFile, []byte(testRuntimeConfig)); err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	if err := VerifyRuntimeConfig(configFile); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	info, err := os.Stat(StatePath(configFile, SigningKeyName))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("signing key mode %o", info.Mode().Perm())
+	}
+
+	// the key is kept, the next deploy signs with it
+	key, err := os.ReadFile(StatePath(configFile, SigningKeyName))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SignRuntimeConfig(configFile, []byte(testRuntimeConfig)); err != nil {
+		t.Fatalf("sign again: %v", err)
+	}
+
+	if again, _ := os.ReadFile(StatePath(configFile, SigningKeyName)); string(again) != string(key) {
+		t.Error("signing key is regenerated")
+	}
+}
+
+func TestVerifyRuntimeConfigTampered(t *testing.T) {
+	configFile := deployTestConfig(t, testRuntimeConfig)
+
+	if err := SignRuntimeConfig(configFile, []byte(testRuntimeConfig)); err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	tampered := testRuntimeConfig + "      ContractAddress: \"0x0000000000000000000000000000000000000001\"\n"
+	if err := os.WriteFile(StatePath(configFile, RuntimeConfigName), []byte(tampered), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := VerifyRuntimeConfig(configFile); !errors.Is(err, ErrConfigChanged) {
+		t.Errorf("error %v, want %v", err, ErrConfigChanged)
+	}
+
+	// Utils.Encryption of the mounted config doesn't sign it
+	forged, err := utils.NewEncryptor("test-encryption-key", "test-encryption-salt", DefaultCipherMethod).Sign([]byte(tampered))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(StatePath(configFile, SignatureName), []byte(forged+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := VerifyRuntimeConfig(configFile); !errors.Is(err, ErrConfigChanged) {
+		t.Errorf("forged signature error %v, want %v", err, ErrConfigChanged)
+	}
+}
+
+func TestVerifyRuntimeConfigNotSigned(t *testing.T) {
+	configFile := deployTestConfig(t, testRuntimeConfig)
+
+	if err := VerifyRuntimeConfig(configFile); !errors.Is(err, ErrNotSigned) {
+		t.Errorf("error %v, want %v", err, ErrNotSigned)
+	}
+
+	if err := SignRuntimeConfig(configFile, []byte(testRuntimeConfig)); err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	// a signature without the key, e.g. written by an older builder
+	if err := os.Remove(StatePath(configFile, SigningKeyName)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := VerifyRuntimeConfig(configFile); !errors.Is(err, ErrNotSigned) {
+		t.Errorf("error %v, want %v", err, ErrNotSigned)
+	}
+}
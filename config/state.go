@@ -0,0 +1,61 @@
+package config
+
+import (
+	"asterizm/builder/utils"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path"
+)
+
+const (
+	// StateDirName is a directory next to the source config with generated files
+	StateDirName      = ".asterizm"
+	RuntimeConfigName = "config.yml"
+	DockerComposeName = "docker-compose.yml"
+)
+
+// StateDir returns managed state directory of the source config
+func StateDir(configFile string) string {
+	return path.Join(path.Dir(configFile), StateDirName)
+}
+
+// StatePath returns path of the generated file in the state directory
+func StatePath(configFile, name string) string {
+	return path.Join(StateDir(configFile), name)
+}
+
+// WriteStateFile writes the generated file readable by its owner only
+// the runtime config and docker-compose.yml hold the encryption key and salt and the db password
+func WriteStateFile(name string, data []byte) error {
+	if err := utils.WriteFileAtomic(name, data, 0600); err != nil {
+		return err
+	}
+
+	// WriteFileAtomic keeps the mode of an existing file, e.g. written by an older builder
+	return os.Chmod(name, 0600)
+}
+
+// loadGenerated reads values generated by the previous run,
+// so reruns reuse the same encryption and db credentials
+func loadGenerated(configFile string) (*Utils, error) {
+	data, err := os.ReadFile(StatePath(configFile, RuntimeConfigName))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &Utils{}, nil
+		}
+
+		return nil, fmt.Errorf("error reading runtime config: %w", err)
+	}
+
+	runtime := &struct {
+		Utils Utils `yaml:"Utils"`
+	}{}
+
+	if err := yaml.Unmarshal(data, runtime); err != nil {
+		return nil, fmt.Errorf("error unmarshaling runtime config: %w", err)
+	}
+
+	return &runtime.Utils, nil
+}
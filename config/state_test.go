@@ -0,0 +1,33 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteStateFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), RuntimeConfigName)
+
+	// the runtime config of an older builder is world-readable
+	if err := os.WriteFile(name, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteStateFile(name, []byte("new")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	info, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode %o, want 600", info.Mode().Perm())
+	}
+
+	if data, _ := os.ReadFile(name); string(data) != "new" {
+		t.Errorf("data %q", data)
+	}
+}
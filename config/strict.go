@@ -0,0 +1,131 @@
+package config
+
+import (
+	"asterizm/builder/utils"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"reflect"
+	"strings"
+)
+
+type UnknownKeyError struct {
+	File       string
+	Line       int
+	Column     int
+	Path       string
+	Key        string
+	Suggestion string
+}
+
+func (e *UnknownKeyError) Error() string {
+	message := fmt.Sprintf("%s:%d:%d: unknown field %q in %s", e.File, e.Line, e.Column, e.Key, e.Path)
+	if e.Suggestion != "" {
+		message += fmt.Sprintf(", did you mean %q?", e.Suggestion)
+	}
+
+	return message
+}
+
+// unknownKeys walks yaml tree and reports keys which are absent in the target struct
+func unknownKeys(file string, root *yaml.Node, target reflect.Type) []error {
+	var errs []error
+	walkNode(file, root, target, "", &errs)
+
+	return errs
+}
+
+func walkNode(file string, node *yaml.Node, target reflect.Type, path string, errs *[]error) {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+
+	for target.Kind() == reflect.Pointer {
+		target = target.Elem()
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			walkNode(file, child, target, path, errs)
+		}
+	case yaml.SequenceNode:
+		if target.Kind() != reflect.Slice && target.Kind() != reflect.Array {
+			return
+		}
+
+		for i, child := range node.Content {
+			walkNode(file, child, target.Elem(), fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	case yaml.MappingNode:
+		switch target.Kind() {
+		case reflect.Map:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				walkNode(file, node.Content[i+1], target.Elem(), joinPath(path, node.Content[i].Value), errs)
+			}
+		case reflect.Struct:
+			fields := yamlFields(target)
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key := node.Content[i]
+				if key.Value == "<<" {
+					continue
+				}
+
+				field, ok := fields[key.Value]
+				if !ok {
+					*errs = append(*errs, &UnknownKeyError{
+						File:       file,
+						Line:       key.Line,
+						Column:     key.Column,
+						Path:       pathOrRoot(path),
+						Key:        key.Value,
+						Suggestion: utils.ClosestString(key.Value, utils.SortedKeys(fields)),
+					})
+					continue
+				}
+
+				walkNode(file, node.Content[i+1], field.Type, joinPath(path, key.Value), errs)
+			}
+		}
+	}
+}
+
+// yamlFields returns struct fields by their yaml names
+func yamlFields(target reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField, target.NumField())
+
+	for i := 0; i < target.NumField(); i++ {
+		field := target.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		fields[name] = field
+	}
+
+	return fields
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "config root"
+	}
+
+	return path
+}
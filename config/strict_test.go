@@ -0,0 +1,75 @@
+package config
+
+import (
+	"errors"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestUnknownKeys(t *testing.T) {
+	root := &yaml.Node{}
+	if err := yaml.Unmarshal([]byte(`Environment:
+  LogLevel: DEBUG
+Nodes:
+  List:
+    ETH:
+      RPC: https://eth.example.com
+      MaxResendTry: 3
+      Fireblocks:
+        ApiKye: key
+Utils:
+  Encryption:
+    Key: key
+  Cache: true
+`), root); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := unknownKeys("config.yml", root, reflect.TypeOf(&Config{}))
+
+	expected := []UnknownKeyError{
+		{File: "config.yml", Line: 7, Column: 7, Path: "Nodes.List.ETH", Key: "MaxResendTry", Suggestion: "MaxResendTries"},
+		{File: "config.yml", Line: 9, Column: 9, Path: "Nodes.List.ETH.Fireblocks", Key: "ApiKye", Suggestion: "ApiKey"},
+		{File: "config.yml", Line: 13, Column: 3, Path: "Utils", Key: "Cache"},
+	}
+
+	if len(errs) != len(expected) {
+		t.Fatalf("errors %v, want %d", errs, len(expected))
+	}
+
+	for i, err := range errs {
+		var unknownKey *UnknownKeyError
+		if !errors.As(err, &unknownKey) {
+			t.Fatalf("error %T", err)
+		}
+
+		if *unknownKey != expected[i] {
+			t.Errorf("error %+v, want %+v", *unknownKey, expected[i])
+		}
+	}
+
+	message := `config.yml:7:7: unknown field "MaxResendTry" in Nodes.List.ETH, did you mean "MaxResendTries"?`
+	if errs[0].Error() != message {
+		t.Errorf("message %q, want %q", errs[0].Error(), message)
+	}
+}
+
+func TestExampleConfigIsStrict(t *testing.T) {
+	data, err := os.ReadFile("../config.example.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(configFile, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var unknownKey *UnknownKeyError
+	if _, err := ParseAndRefreshConfig("db", configFile, false); errors.As(err, &unknownKey) {
+		t.Errorf("config.example.yml: %v", err)
+	}
+}
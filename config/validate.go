@@ -0,0 +1,206 @@
+package config
+
+import (
+	"asterizm/builder/networks"
+	"asterizm/builder/utils"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// nodeField describes a family specific Node field
+// applicability and requirement come from the networks registry
+type nodeField struct {
+	name string
+	// mandatory only when OwnerPrivateKey is set
+	ownerOnly bool
+	value     func(node *Node) *string
+}
+
+var nodeFields = []nodeField{
+	{
+		name:  "ContractAddress",
+		value: func(node *Node) *string { return node.ContractAddress },
+	},
+	{
+		name:  "ArchiveRPC",
+		value: func(node *Node) *string { return node.ArchiveRpc },
+	},
+	{
+		name:      "OwnerPublicKey",
+		ownerOnly: true,
+		value:     func(node *Node) *string { return node.OwnerPublicKey },
+	},
+	{
+		name:      "OwnerWalletType",
+		ownerOnly: true,
+		value:     func(node *Node) *string { return node.OwnerWalletType },
+	},
+	{
+		name:  "TokenProgramId",
+		value: func(node *Node) *string { return node.TokenProgramId },
+	},
+	{
+		name:  "TokenName",
+		value: func(node *Node) *string { return node.TokenName },
+	},
+	{
+		name:  "ClientProgramId",
+		value: func(node *Node) *string { return node.ClientProgramId },
+	},
+	{
+		name:  "ClientUserAddress",
+		value: func(node *Node) *string { return node.ClientUserAddress },
+	},
+	{
+		name:  "InitializerProgramId",
+		value: func(node *Node) *string { return node.InitializerProgramId },
+	},
+	{
+		name:  "RelayerProgramId",
+		value: func(node *Node) *string { return node.RelayerProgramId },
+	},
+	{
+		name:  "SystemRelayOwnerAddress",
+		value: func(node *Node) *string { return node.SystemRelayOwnerAddress },
+	},
+	{
+		name:  "RelayOwnerAddress",
+		value: func(node *Node) *string { return node.RelayOwnerAddress },
+	},
+}
+
+// Validate checks family specific node fields
+// returns warnings about fields which are not applicable to the network family
+func (n *Node) Validate(network string) ([]string, error) {
+	if n.RPC == "" {
+		return nil, fmt.Errorf("please, fill Nodes.List.%s.RPC", network)
+	}
+
+	registered, ok := networks.Lookup(network)
+	if !ok {
+		message := fmt.Sprintf("unknown network Nodes.List.%s", network)
+		if suggestion := networks.Suggest(network); suggestion != "" {
+			message += fmt.Sprintf(", did you mean %q?", suggestion)
+		}
+		return nil, fmt.Errorf("%s (supported networks: %s)", message, strings.Join(networks.Codes(), ", "))
+	}
+
+	var (
+		warnings []string
+		errs     []error
+	)
+
+	for _, field := range nodeFields {
+		value := field.value(n)
+		isSet := value != nil && *value != ""
+
+		if !registered.IsApplicable(field.name) {
+			if isSet {
+				warnings = append(warnings, fmt.Sprintf("Nodes.List.%s.%s is not applicable to %s networks and will be ignored", network, field.name, registered.Family))
+			}
+			continue
+		}
+
+		if isSet || !registered.IsRequired(field.name) {
+			continue
+		}
+
+		if field.ownerOnly && (n.OwnerPrivateKey == nil || *n.OwnerPrivateKey == "") {
+			continue
+		}
+
+		errs = append(errs, fmt.Errorf("please, fill Nodes.List.%s.%s", network, field.name))
+	}
+
+	if registered.Family == networks.FamilyTON && n.OwnerWalletType != nil && *n.OwnerWalletType != "" && !utils.InSlice(*n.OwnerWalletType, networks.TonWalletTypes) {
+		errs = append(errs, fmt.Errorf(
+			"Nodes.List.%s.OwnerWalletType %q is not supported, available types: %s",
+			network,
+			*n.OwnerWalletType,
+			strings.Join(networks.TonWalletTypes, "/"),
+		))
+	}
+
+	return warnings, errors.Join(errs...)
+}
+
+// Validate checks all nodes and collects their warnings and errors
+func (c *Config) Validate() ([]string, error) {
+	if len(c.Nodes.List) == 0 {
+		return nil, errors.New("please, fill Nodes.List")
+	}
+
+	var (
+		warnings []string
+		errs     []error
+	)
+
+	if c.Utils.Encryption != nil {
+		if err := utils.ValidateCipherMethod(c.Utils.Encryption.CipherMethod); err != nil {
+			errs = append(errs, fmt.Errorf("Utils.Encryption.CipherMethod: %w", err))
+		}
+
+		if err := c.Utils.Encryption.kdf().Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("Utils.Encryption.Kdf: %w", err))
+		}
+	}
+
+	if err := c.validateDeployment(); err != nil {
+		errs = append(errs, err)
+	}
+
+	for _, key := range utils.SortedKeys(c.Nodes.List) {
+		node := c.Nodes.List[key]
+		nodeWarnings, err := node.Validate(key)
+		warnings = append(warnings, nodeWarnings...)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return warnings, errors.Join(errs...)
+}
+
+// CheckNetworkType warns about networks which don't match the selected mainnet or testnet
+// chain ids reported by the RPC endpoints are compared with the registry, the RPC url is checked for networks without them
+func (c *Config) CheckNetworkType(isTest bool, chainIds map[string]string) []string {
+	var warnings []string
+
+	kind, flag := "mainnet", "-test flag is not set"
+	if isTest {
+		kind, flag = "testnet", "-test flag is set"
+	}
+
+	for _, key := range utils.SortedKeys(c.Nodes.List) {
+		node := c.Nodes.List[key]
+		network, ok := networks.Lookup(key)
+		if !ok {
+			continue
+		}
+
+		// e.g. EVER and VNM have no testnet for the seed
+		if len(network.ChainIds(isTest)) == 0 && len(network.ChainIds(!isTest)) > 0 {
+			warnings = append(warnings, fmt.Sprintf("Nodes.List.%s: the network has no known %s, but %s", key, kind, flag))
+			continue
+		}
+
+		if chainId, ok := chainIds[network.Code]; ok {
+			if chainKind := network.Kind(chainId); chainKind != "" && chainKind != kind {
+				warnings = append(warnings, fmt.Sprintf("Nodes.List.%s.RPC is a %s rpc (chain id %s), but %s", key, chainKind, chainId, flag))
+			}
+			continue
+		}
+
+		isTestnetRpc := networks.LooksLikeTestnet(node.RPC)
+		if isTest && !isTestnetRpc && strings.Contains(strings.ToLower(node.RPC), "mainnet") {
+			warnings = append(warnings, fmt.Sprintf("Nodes.List.%s.RPC looks like a mainnet url, but -test flag is set", key))
+		}
+
+		if !isTest && isTestnetRpc {
+			warnings = append(warnings, fmt.Sprintf("Nodes.List.%s.RPC looks like a testnet url, but -test flag is not set", key))
+		}
+	}
+
+	return warnings
+}
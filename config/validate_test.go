@@ -0,0 +1,135 @@
+package config
+
+import (
+	"asterizm/builder/utils"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheckNetworkType(t *testing.T) {
+	cfg := parseTestConfig(t, `Nodes:
+  List:
+    ETH:
+      RPC: https://eth-sepolia.example.com
+    BSC:
+      RPC: https://bsc-mainnet.example.com
+    EVER:
+      RPC: https://ever.example.com
+`)
+
+	for _, test := range []struct {
+		name     string
+		isTest   bool
+		chainIds map[string]string
+		warnings []string
+	}{
+		{
+			name: "mainnet by urls",
+			warnings: []string{
+				"Nodes.List.ETH.RPC looks like a testnet url, but -test flag is not set",
+			},
+		},
+		{
+			name:   "testnet by urls",
+			isTest: true,
+			warnings: []string{
+				"Nodes.List.BSC.RPC looks like a mainnet url, but -test flag is set",
+				"Nodes.List.EVER: the network has no known testnet, but -test flag is set",
+			},
+		},
+		{
+			// the reported chain ids take precedence over the urls
+			name:     "mainnet by chain ids",
+			chainIds: map[string]string{"ETH": "1", "BSC": "97"},
+			warnings: []string{
+				"Nodes.List.BSC.RPC is a testnet rpc (chain id 97), but -test flag is not set",
+			},
+		},
+		{
+			name:     "testnet by chain ids",
+			isTest:   true,
+			chainIds: map[string]string{"ETH": "11155111", "BSC": "97"},
+			warnings: []string{
+				"Nodes.List.EVER: the network has no known testnet, but -test flag is set",
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			warnings := cfg.CheckNetworkType(test.isTest, test.chainIds)
+			if !reflect.DeepEqual(warnings, test.warnings) {
+				t.Errorf("warnings %q, want %q", warnings, test.warnings)
+			}
+		})
+	}
+}
+
+func TestCanonicalizeNetworks(t *testing.T) {
+	cfg := parseTestConfig(t, `Nodes:
+  List:
+    Ton: # toncenter
+      RPC: https://ton.example.com
+    eth:
+      RPC: https://eth.example.com
+    SOL:
+      RPC: https://sol.example.com
+`)
+
+	if err := cfg.canonicalizeNetworks(); err != nil {
+		t.Fatalf("canonicalize: %v", err)
+	}
+
+	if keys := strings.Join(utils.SortedKeys(cfg.Nodes.List), ","); keys != "ETH,SOL,TON" {
+		t.Errorf("networks %s", keys)
+	}
+
+	if rpc := cfg.Nodes.List["TON"].RPC; rpc != "https://ton.example.com" {
+		t.Errorf("TON RPC %q", rpc)
+	}
+
+	rendered, err := cfg.document.render()
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+
+	expected := `Nodes:
+  List:
+    TON: # toncenter
+      RPC: https://ton.example.com
+    ETH:
+      RPC: https://eth.example.com
+    SOL:
+      RPC: https://sol.example.com
+`
+	if string(rendered) != expected {
+		t.Errorf("rendered:\n%s\nwant:\n%s", rendered, expected)
+	}
+}
+
+func TestCanonicalizeNetworksRejectsDuplicates(t *testing.T) {
+	cfg := parseTestConfig(t, `Nodes:
+  List:
+    ETH:
+      RPC: https://eth.example.com
+    eth:
+      RPC: https://eth-2.example.com
+    Sol:
+      RPC: https://sol.example.com
+    sol:
+      RPC: https://sol-2.example.com
+`)
+
+	err := cfg.canonicalizeNetworks()
+	if err == nil {
+		t.Fatal("duplicates are not reported")
+	}
+
+	for _, message := range []string{
+		`duplicate network Nodes.List.ETH: "ETH" and "eth"`,
+		`duplicate network Nodes.List.SOL: "Sol" and "sol"`,
+	} {
+		if !strings.Contains(err.Error(), message) {
+			t.Errorf("error %q doesn't contain %q", err, message)
+		}
+	}
+}
@@ -2,7 +2,9 @@ package dockercompose
 
 import (
 	"asterizm/builder/config"
+	"asterizm/builder/networks"
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -11,6 +13,10 @@ const (
 	AsterizmConsole = "asterizm-cs-console"
 	AsterizmCron    = "asterizm-cs-cron"
 	AsterizmScanner = "asterizm-cs-scanner-%s"
+
+	// the config package is shadowed by the InitFromConfig argument
+	clientServerImage = config.ClientServerImage
+	postgresImage     = config.PostgresImage
 )
 
 type Service struct {
@@ -24,6 +30,15 @@ type Service struct {
 	Environment   map[string]any `yaml:"environment,omitempty"`
 	HealthCheck   map[string]any `yaml:"healthcheck,omitempty"`
 	Restart       string         `yaml:"restart,omitempty"`
+
+	// limits are written both to deploy.resources and the service keys, compose requires them to be equal
+	Deploy          map[string]any `yaml:"deploy,omitempty"`
+	Cpus            string         `yaml:"cpus,omitempty"`
+	MemLimit        string         `yaml:"mem_limit,omitempty"`
+	MemReservation  string         `yaml:"mem_reservation,omitempty"`
+	PidsLimit       int64          `yaml:"pids_limit,omitempty"`
+	Ulimits         map[string]any `yaml:"ulimits,omitempty"`
+	StopGracePeriod string         `yaml:"stop_grace_period,omitempty"`
 }
 
 type DockerCompose struct {
@@ -33,12 +48,20 @@ type DockerCompose struct {
 	Services map[string]Service           `yaml:"services"`
 }
 
-func InitFromConfig(configPath string, config *config.Config) *DockerCompose {
+func InitFromConfig(configPath string, config *config.Config) (*DockerCompose, error) {
 	asterizmNetwork := "asterizm-cs"
 	dbDataVolume := "aterizm-cs-dbdata"
 
-	asterizmImage := "asterizm/client-server:latest"
-	configVolume := configPath + ":" + "/app/config.yml:rw"
+	asterizmImage := config.Image(clientServerImage).Reference()
+	// read-only, the signature check relies on the config being changed by the builder only
+	configVolume := configPath + ":" + "/app/config.yml:ro"
+
+	// the client-server services are checked only with the configured test, the image has no health signal
+	healthCheckSettings := config.HealthCheck()
+	var asterizmHealthCheck map[string]any
+	if len(healthCheckSettings.Test) > 0 {
+		asterizmHealthCheck = healthCheck(healthCheckSettings.Test, healthCheckSettings)
+	}
 
 	dockerCompose := &DockerCompose{
 		Version: "3.9",
@@ -56,7 +79,7 @@ func InitFromConfig(configPath string, config *config.Config) *DockerCompose {
 	if config.Utils.Db.Host == DbHost {
 		dockerCompose.Services[DbHost] = Service{
 			ContainerName: DbHost,
-			Image:         "postgres:15-alpine",
+			Image:         config.Image(postgresImage).Reference(),
 			Networks:      []string{asterizmNetwork},
 			Volumes:       []string{dbDataVolume + ":/var/lib/postgresql/data"},
 			Environment: map[string]any{
@@ -65,13 +88,8 @@ func InitFromConfig(configPath string, config *config.Config) *DockerCompose {
 				"POSTGRES_DB":       config.Utils.Db.Name,
 				"POSTGRES_PORT":     config.Utils.Db.Port,
 			},
-			Restart: "always",
-			HealthCheck: map[string]any{
-				"test":     []string{"CMD-SHELL", "pg_isready -U postgres"},
-				"interval": "5s",
-				"retries":  3,
-			},
-		}
+			HealthCheck: healthCheck([]string{"CMD", "pg_isready", "-U", config.Utils.Db.User, "-d", config.Utils.Db.Name}, healthCheckSettings),
+		}.withResources(config.PostgresResources())
 
 		asterizmDependOn[DbHost] = map[string]string{
 			"condition": "service_healthy",
@@ -84,8 +102,8 @@ func InitFromConfig(configPath string, config *config.Config) *DockerCompose {
 		Volumes:       []string{configVolume},
 		Networks:      []string{asterizmNetwork},
 		DependsOn:     asterizmDependOn,
-		Restart:       "always",
-	}
+		HealthCheck:   asterizmHealthCheck,
+	}.withResources(config.ConsoleResources())
 
 	dockerCompose.Services[AsterizmCron] = Service{
 		ContainerName: AsterizmCron,
@@ -94,11 +112,16 @@ func InitFromConfig(configPath string, config *config.Config) *DockerCompose {
 		Networks:      []string{asterizmNetwork},
 		Command:       []string{"cron/process"},
 		DependsOn:     asterizmDependOn,
-		Restart:       "always",
-	}
+		HealthCheck:   asterizmHealthCheck,
+	}.withResources(config.CronResources())
 
-	for key, _ := range config.Nodes.List {
-		containerName := fmt.Sprintf(AsterizmScanner, strings.ToLower(key))
+	for key := range config.Nodes.List {
+		network, ok := networks.Lookup(key)
+		if !ok {
+			return nil, fmt.Errorf("unknown network %s", key)
+		}
+
+		containerName := fmt.Sprintf(AsterizmScanner, strings.ToLower(network.Code))
 
 		dockerCompose.Services[containerName] = Service{
 			ContainerName: containerName,
@@ -106,10 +129,70 @@ func InitFromConfig(configPath string, config *config.Config) *DockerCompose {
 			Volumes:       []string{configVolume},
 			Networks:      []string{asterizmNetwork},
 			DependsOn:     asterizmDependOn,
-			Command:       []string{"node/scan", strings.ToUpper(key)},
-			Restart:       "always",
+			Command:       []string{"node/scan", network.Code},
+			HealthCheck:   asterizmHealthCheck,
+		}.withResources(config.ScannerResources(network.Code))
+	}
+
+	return dockerCompose, nil
+}
+
+// healthCheck returns compose healthcheck of the test with the configured timings
+func healthCheck(test []string, settings config.HealthCheck) map[string]any {
+	return map[string]any{
+		"test":         test,
+		"interval":     settings.Interval,
+		"timeout":      settings.Timeout,
+		"retries":      settings.Retries,
+		"start_period": settings.StartPeriod,
+	}
+}
+
+// withResources applies limits and the restart policy to the service
+func (s Service) withResources(resources config.Resources) Service {
+	s.Restart = resources.RestartPolicy()
+	if s.Restart == "on-failure" && resources.MaxRestarts > 0 {
+		s.Restart += ":" + strconv.FormatUint(resources.MaxRestarts, 10)
+	}
+
+	s.Cpus = resources.Cpus
+	s.MemLimit = resources.Memory
+	s.MemReservation = resources.MemoryReservation
+	s.PidsLimit = resources.Pids
+	s.StopGracePeriod = resources.StopGracePeriod
+
+	if resources.Nofile > 0 {
+		s.Ulimits = map[string]any{
+			"nofile": map[string]uint64{"soft": resources.Nofile, "hard": resources.Nofile},
 		}
 	}
 
-	return dockerCompose
+	limits := make(map[string]any)
+	if resources.Cpus != "" {
+		limits["cpus"] = resources.Cpus
+	}
+	if resources.Memory != "" {
+		limits["memory"] = resources.Memory
+	}
+	if resources.Pids > 0 {
+		limits["pids"] = resources.Pids
+	}
+
+	deploy := make(map[string]any)
+	if len(limits) > 0 || resources.MemoryReservation != "" {
+		deployResources := map[string]any{}
+		if len(limits) > 0 {
+			deployResources["limits"] = limits
+		}
+		if resources.MemoryReservation != "" {
+			deployResources["reservations"] = map[string]any{"memory": resources.MemoryReservation}
+		}
+		deploy["resources"] = deployResources
+	}
+
+	if len(deploy) > 0 {
+		s.Deploy = deploy
+	}
+
+	return s
 }
@@ -0,0 +1,183 @@
+package dockercompose
+
+import (
+	"asterizm/builder/config"
+	"reflect"
+	"testing"
+)
+
+// composeConfig returns a config with a docker db and the networks
+func composeConfig(networks ...string) *config.Config {
+	cfg := &config.Config{}
+	cfg.Utils.Db = &config.Db{Host: DbHost, Port: 5432, Name: "asterizm-cs", User: "asterizm-cs", Password: "test-password"}
+	cfg.Nodes.List = make(map[string]config.Node)
+	for _, network := range networks {
+		cfg.Nodes.List[network] = config.Node{RPC: "https://rpc.example.com"}
+	}
+
+	return cfg
+}
+
+func TestHealthChecks(t *testing.T) {
+	clientServices := []string{AsterizmConsole, AsterizmCron, "asterizm-cs-scanner-eth", "asterizm-cs-scanner-ton"}
+
+	t.Run("default", func(t *testing.T) {
+		compose, err := InitFromConfig("./config.yml", composeConfig("ETH", "TON"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := map[string]any{
+			"test":         []string{"CMD", "pg_isready", "-U", "asterizm-cs", "-d", "asterizm-cs"},
+			"interval":     "10s",
+			"timeout":      "5s",
+			"retries":      3,
+			"start_period": "10s",
+		}
+		if db := compose.Services[DbHost]; !reflect.DeepEqual(db.HealthCheck, want) {
+			t.Errorf("db healthcheck %v, want %v", db.HealthCheck, want)
+		}
+
+		// the client server image has no liveness command
+		for _, name := range clientServices {
+			service, ok := compose.Services[name]
+			if !ok {
+				t.Fatalf("no %s service", name)
+			}
+
+			if service.HealthCheck != nil {
+				t.Errorf("%s healthcheck %v", name, service.HealthCheck)
+			}
+
+			if service.DependsOn[DbHost].(map[string]string)["condition"] != "service_healthy" {
+				t.Errorf("%s doesn't wait for the healthy db", name)
+			}
+		}
+	})
+
+	t.Run("configured", func(t *testing.T) {
+		cfg := composeConfig("ETH", "TON")
+		cfg.Deployment.HealthCheck = &config.HealthCheck{Interval: "30s", Retries: 5, Test: []string{"CMD-SHELL", "./main status"}}
+
+		compose, err := InitFromConfig("./config.yml", cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		timings := map[string]any{"interval": "30s", "timeout": "5s", "retries": 5, "start_period": "10s"}
+		for name, test := range map[string][]string{
+			// the test of the client server services isn't applied to postgres
+			DbHost:                    {"CMD", "pg_isready", "-U", "asterizm-cs", "-d", "asterizm-cs"},
+			AsterizmConsole:           {"CMD-SHELL", "./main status"},
+			AsterizmCron:              {"CMD-SHELL", "./main status"},
+			"asterizm-cs-scanner-eth": {"CMD-SHELL", "./main status"},
+			"asterizm-cs-scanner-ton": {"CMD-SHELL", "./main status"},
+		} {
+			want := map[string]any{"test": test}
+			for k, v := range timings {
+				want[k] = v
+			}
+
+			if healthCheck := compose.Services[name].HealthCheck; !reflect.DeepEqual(healthCheck, want) {
+				t.Errorf("%s healthcheck %v, want %v", name, healthCheck, want)
+			}
+		}
+	})
+
+	t.Run("external db", func(t *testing.T) {
+		cfg := composeConfig("ETH")
+		cfg.Utils.Db.Host = "db.example.com"
+
+		compose, err := InitFromConfig("./config.yml", cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, ok := compose.Services[DbHost]; ok {
+			t.Error("db service is generated for the external db")
+		}
+
+		if dependsOn := compose.Services[AsterizmConsole].DependsOn; len(dependsOn) != 0 {
+			t.Errorf("console depends on %v", dependsOn)
+		}
+	})
+}
+
+func TestResources(t *testing.T) {
+	cfg := composeConfig("ETH", "TON")
+	cfg.Deployment.Resources = config.ServiceResources{
+		Default:  &config.Resources{Cpus: "0.5", Memory: "512m", MemoryReservation: "256m", Pids: 256, Restart: "on-failure", MaxRestarts: 10},
+		Console:  &config.Resources{Memory: "256m"},
+		Postgres: &config.Resources{Memory: "1g", Nofile: 65536},
+		Networks: map[string]*config.Resources{"eth": {Cpus: "1.5", Pids: 512}},
+	}
+
+	compose, err := InitFromConfig("./config.yml", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]Service{
+		DbHost:                    {MemLimit: "1g", Restart: "always"},
+		AsterizmConsole:           {Cpus: "0.5", MemLimit: "256m", MemReservation: "256m", PidsLimit: 256, Restart: "on-failure:10"},
+		AsterizmCron:              {Cpus: "0.5", MemLimit: "512m", MemReservation: "256m", PidsLimit: 256, Restart: "on-failure:10"},
+		"asterizm-cs-scanner-eth": {Cpus: "1.5", MemLimit: "512m", MemReservation: "256m", PidsLimit: 512, Restart: "on-failure:10"},
+		"asterizm-cs-scanner-ton": {Cpus: "0.5", MemLimit: "512m", MemReservation: "256m", PidsLimit: 256, Restart: "on-failure:10"},
+	} {
+		service := compose.Services[name]
+		if service.Cpus != want.Cpus || service.MemLimit != want.MemLimit || service.MemReservation != want.MemReservation || service.PidsLimit != want.PidsLimit || service.Restart != want.Restart {
+			t.Errorf("%s: cpus %q, mem_limit %q, mem_reservation %q, pids_limit %d, restart %q, want %+v",
+				name, service.Cpus, service.MemLimit, service.MemReservation, service.PidsLimit, service.Restart, want)
+		}
+
+		// compose rejects service keys which differ from deploy.resources
+		resources, _ := service.Deploy["resources"].(map[string]any)
+		limits, _ := resources["limits"].(map[string]any)
+		if limits["cpus"] != nonEmpty(service.Cpus) || limits["memory"] != nonEmpty(service.MemLimit) || limits["pids"] != nonZero(service.PidsLimit) {
+			t.Errorf("%s: deploy.resources.limits %v differ from cpus %q, mem_limit %q, pids_limit %d", name, limits, service.Cpus, service.MemLimit, service.PidsLimit)
+		}
+
+		reservations, _ := resources["reservations"].(map[string]any)
+		if reservations["memory"] != nonEmpty(service.MemReservation) {
+			t.Errorf("%s: deploy.resources.reservations %v differ from mem_reservation %q", name, reservations, service.MemReservation)
+		}
+
+		// the restart policy of swarm isn't applied by docker compose up
+		if _, ok := service.Deploy["restart_policy"]; ok {
+			t.Errorf("%s: deploy.restart_policy is generated", name)
+		}
+	}
+
+	if ulimits := compose.Services[DbHost].Ulimits; !reflect.DeepEqual(ulimits, map[string]any{"nofile": map[string]uint64{"soft": 65536, "hard": 65536}}) {
+		t.Errorf("db ulimits %v", ulimits)
+	}
+
+	// unlimited services have no deploy section
+	compose, err = InitFromConfig("./config.yml", composeConfig("ETH"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, service := range compose.Services {
+		if service.Deploy != nil || service.Restart != config.DefaultRestart {
+			t.Errorf("%s: deploy %v, restart %q", name, service.Deploy, service.Restart)
+		}
+	}
+}
+
+// nonEmpty returns nil for the absent value, as it's absent in the limits map
+func nonEmpty(value string) any {
+	if value == "" {
+		return nil
+	}
+
+	return value
+}
+
+func nonZero(value int64) any {
+	if value == 0 {
+		return nil
+	}
+
+	return value
+}
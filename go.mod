@@ -3,6 +3,8 @@ module asterizm/builder
 go 1.20
 
 require (
+	golang.org/x/crypto v0.12.0
 	golang.org/x/sys v0.11.0
+	golang.org/x/term v0.11.0
 	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,61 @@
+package keys
+
+import (
+	"fmt"
+	"math/big"
+)
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var base58Radix = big.NewInt(58)
+
+func encodeBase58(data []byte) string {
+	value := new(big.Int).SetBytes(data)
+	mod := new(big.Int)
+
+	var result []byte
+	for value.Sign() > 0 {
+		value.DivMod(value, base58Radix, mod)
+		result = append(result, base58Alphabet[mod.Int64()])
+	}
+
+	for _, b := range data {
+		if b != 0 {
+			break
+		}
+		result = append(result, base58Alphabet[0])
+	}
+
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+
+	return string(result)
+}
+
+func decodeBase58(str string) ([]byte, error) {
+	value := new(big.Int)
+	for _, r := range str {
+		index := -1
+		for i, c := range base58Alphabet {
+			if c == r {
+				index = i
+				break
+			}
+		}
+
+		if index < 0 {
+			return nil, fmt.Errorf("invalid base58 character %q", r)
+		}
+
+		value.Mul(value, base58Radix)
+		value.Add(value, big.NewInt(int64(index)))
+	}
+
+	zeros := 0
+	for zeros < len(str) && str[zeros] == base58Alphabet[0] {
+		zeros++
+	}
+
+	return append(make([]byte, zeros), value.Bytes()...), nil
+}
@@ -0,0 +1,55 @@
+package keys
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// vectors of bitcoin core base58_encode_decode.json
+func TestBase58(t *testing.T) {
+	for _, test := range []struct {
+		hex     string
+		encoded string
+	}{
+		{hex: "", encoded: ""},
+		{hex: "61", encoded: "2g"},
+		{hex: "626262", encoded: "a3gV"},
+		{hex: "636363", encoded: "aPEr"},
+		{hex: "73696d706c792061206c6f6e6720737472696e67", encoded: "2cFupjhnEsSn59qHXstmK2ffpLv2"},
+		{hex: "00eb15231dfceb60925886b67d065299925915aeb172c06647", encoded: "1NS17iag9jJgTHD1VXjvLCEnZuQ3rJDE9L"},
+		{hex: "516b6fcd0f", encoded: "ABnLTmg"},
+		{hex: "bf4f89001e670274dd", encoded: "3SEo3LWLoPntC"},
+		{hex: "572e4794", encoded: "3EFU7m"},
+		{hex: "ecac89cad93923c02321", encoded: "EJDM8drfXA6uyA"},
+		{hex: "10c8511e", encoded: "Rt5zm"},
+		// leading zero bytes are kept as leading ones
+		{hex: "00000000000000000000", encoded: "1111111111"},
+		{hex: "0000287fb4cd", encoded: "11233QC4"},
+	} {
+		data, _ := hex.DecodeString(test.hex)
+
+		if encoded := encodeBase58(data); encoded != test.encoded {
+			t.Errorf("encode %s = %q, want %q", test.hex, encoded, test.encoded)
+		}
+
+		decoded, err := decodeBase58(test.encoded)
+		if err != nil {
+			t.Errorf("decode %q: %v", test.encoded, err)
+			continue
+		}
+
+		if !bytes.Equal(decoded, data) {
+			t.Errorf("decode %q = %x, want %s", test.encoded, decoded, test.hex)
+		}
+	}
+}
+
+func TestDecodeBase58Rejects(t *testing.T) {
+	// 0, O, I and l are not in the alphabet
+	for _, str := range []string{"0", "2gO", "Il", "3EFU7m+"} {
+		if _, err := decodeBase58(str); err == nil {
+			t.Errorf("%q is decoded", str)
+		}
+	}
+}
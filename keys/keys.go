@@ -0,0 +1,178 @@
+package keys
+
+import (
+	"asterizm/builder/networks"
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// Owner is a public part of the owner private key
+type Owner struct {
+	// empty if the address can't be derived for the family
+	Address   string
+	PublicKey string
+}
+
+// Derive returns owner address and public key of the private key
+// walletType is used only by TON networks
+func Derive(network networks.Network, privateKey string, walletType string, isTest bool) (*Owner, error) {
+	privateKey = strings.TrimSpace(privateKey)
+
+	switch network.Family {
+	case networks.FamilyEVM:
+		return deriveEvm(privateKey)
+	case networks.FamilySOL:
+		return deriveSol(privateKey)
+	case networks.FamilyTON:
+		return deriveTon(privateKey, walletType, isTest)
+	case networks.FamilyTVM:
+		seed, err := parseEd25519Seed(privateKey)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Owner{PublicKey: hex.EncodeToString(ed25519.NewKeyFromSeed(seed).Public().(ed25519.PublicKey))}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported network family %s", network.Family)
+}
+
+// SameAddress compares addresses of the family ignoring their formatting
+func SameAddress(family networks.Family, a, b string) bool {
+	a, b = strings.TrimSpace(a), strings.TrimSpace(b)
+
+	switch family {
+	case networks.FamilyEVM:
+		return strings.EqualFold(strings.TrimPrefix(a, "0x"), strings.TrimPrefix(b, "0x"))
+	case networks.FamilyTON:
+		wcA, hashA, errA := parseTonAddress(a)
+		wcB, hashB, errB := parseTonAddress(b)
+		return errA == nil && errB == nil && wcA == wcB && bytes.Equal(hashA, hashB)
+	case networks.FamilyTVM:
+		return strings.EqualFold(strings.TrimPrefix(a, "0x"), strings.TrimPrefix(b, "0x"))
+	}
+
+	return a == b
+}
+
+func deriveEvm(privateKey string) (*Owner, error) {
+	key, err := hex.DecodeString(strings.TrimPrefix(privateKey, "0x"))
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("evm private key must be 32 bytes hex")
+	}
+
+	publicKey, err := secp256k1PublicKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write(publicKey)
+
+	return &Owner{
+		Address:   checksumAddress(hash.Sum(nil)[12:]),
+		PublicKey: "0x04" + hex.EncodeToString(publicKey),
+	}, nil
+}
+
+// checksumAddress returns EIP-55 mixed-case address
+func checksumAddress(address []byte) string {
+	lower := hex.EncodeToString(address)
+
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write([]byte(lower))
+	digest := hash.Sum(nil)
+
+	result := []byte(lower)
+	for i, c := range result {
+		nibble := digest[i/2] >> 4
+		if i%2 == 1 {
+			nibble = digest[i/2] & 0x0f
+		}
+
+		if c >= 'a' && nibble >= 8 {
+			result[i] = c - 32
+		}
+	}
+
+	return "0x" + string(result)
+}
+
+// deriveSol accepts base58 keypair or seed, or json byte array of solana cli
+func deriveSol(privateKey string) (*Owner, error) {
+	var key []byte
+	if strings.HasPrefix(privateKey, "[") {
+		if err := json.Unmarshal([]byte(privateKey), &key); err != nil {
+			return nil, fmt.Errorf("invalid solana key byte array: %w", err)
+		}
+	} else {
+		decoded, err := decodeBase58(privateKey)
+		if err != nil {
+			return nil, err
+		}
+		key = decoded
+	}
+
+	if len(key) != ed25519.SeedSize && len(key) != ed25519.PrivateKeySize {
+		return nil, errors.New("solana private key must be 32 or 64 bytes")
+	}
+
+	publicKey := ed25519.NewKeyFromSeed(key[:ed25519.SeedSize]).Public().(ed25519.PublicKey)
+	if len(key) == ed25519.PrivateKeySize && !bytes.Equal(publicKey, key[ed25519.SeedSize:]) {
+		return nil, errors.New("solana keypair public key doesn't match its secret")
+	}
+
+	address := encodeBase58(publicKey)
+	return &Owner{Address: address, PublicKey: address}, nil
+}
+
+// deriveTon accepts 24 words mnemonic or hex ed25519 key
+func deriveTon(privateKey string, walletType string, isTest bool) (*Owner, error) {
+	var (
+		seed []byte
+		err  error
+	)
+
+	if words := strings.Fields(privateKey); len(words) > 1 {
+		seed, err = tonSeedFromMnemonic(words)
+	} else {
+		seed, err = parseEd25519Seed(privateKey)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey := ed25519.NewKeyFromSeed(seed).Public().(ed25519.PublicKey)
+	owner := &Owner{PublicKey: hex.EncodeToString(publicKey)}
+
+	// e.g. highload wallets depend on parameters which are absent in the config
+	if _, ok := tonWalletCodes[walletType]; !ok {
+		return owner, nil
+	}
+
+	hash, err := tonWalletAddress(publicKey, walletType, isTest)
+	if err != nil {
+		return nil, err
+	}
+
+	owner.Address = formatTonAddress(hash, false, isTest)
+	return owner, nil
+}
+
+// parseEd25519Seed accepts hex seed or hex seed with public key
+func parseEd25519Seed(privateKey string) ([]byte, error) {
+	key, err := hex.DecodeString(strings.TrimPrefix(privateKey, "0x"))
+	if err != nil || (len(key) != ed25519.SeedSize && len(key) != ed25519.PrivateKeySize) {
+		return nil, errors.New("ed25519 private key must be 32 or 64 bytes hex")
+	}
+
+	return key[:ed25519.SeedSize], nil
+}
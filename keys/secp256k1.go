@@ -0,0 +1,84 @@
+package keys
+
+import (
+	"errors"
+	"math/big"
+)
+
+// minimal secp256k1 arithmetic, only used to derive public keys,
+// so constant time execution is not required
+var (
+	secp256k1P, _  = new(big.Int).SetString("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f", 16)
+	secp256k1N, _  = new(big.Int).SetString("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141", 16)
+	secp256k1Gx, _ = new(big.Int).SetString("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798", 16)
+	secp256k1Gy, _ = new(big.Int).SetString("483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8", 16)
+)
+
+type point struct {
+	x, y *big.Int
+}
+
+// secp256k1PublicKey returns uncompressed public key without 0x04 prefix
+func secp256k1PublicKey(privateKey []byte) ([]byte, error) {
+	d := new(big.Int).SetBytes(privateKey)
+	if d.Sign() == 0 || d.Cmp(secp256k1N) >= 0 {
+		return nil, errors.New("private key is out of secp256k1 range")
+	}
+
+	var result *point
+	addend := &point{x: secp256k1Gx, y: secp256k1Gy}
+	for i := 0; i < d.BitLen(); i++ {
+		if d.Bit(i) == 1 {
+			result = addPoints(result, addend)
+		}
+		addend = addPoints(addend, addend)
+	}
+
+	publicKey := make([]byte, 64)
+	result.x.FillBytes(publicKey[:32])
+	result.y.FillBytes(publicKey[32:])
+
+	return publicKey, nil
+}
+
+func addPoints(a, b *point) *point {
+	if a == nil {
+		return b
+	}
+
+	if b == nil {
+		return a
+	}
+
+	slope := new(big.Int)
+	if a.x.Cmp(b.x) == 0 {
+		if a.y.Cmp(b.y) != 0 || a.y.Sign() == 0 {
+			return nil
+		}
+
+		// 3x^2 / 2y
+		slope.Mul(a.x, a.x)
+		slope.Mul(slope, big.NewInt(3))
+		denominator := new(big.Int).Lsh(a.y, 1)
+		slope.Mul(slope, denominator.ModInverse(denominator, secp256k1P))
+	} else {
+		// (y2 - y1) / (x2 - x1)
+		slope.Sub(b.y, a.y)
+		denominator := new(big.Int).Sub(b.x, a.x)
+		denominator.Mod(denominator, secp256k1P)
+		slope.Mul(slope, denominator.ModInverse(denominator, secp256k1P))
+	}
+	slope.Mod(slope, secp256k1P)
+
+	x := new(big.Int).Mul(slope, slope)
+	x.Sub(x, a.x)
+	x.Sub(x, b.x)
+	x.Mod(x, secp256k1P)
+
+	y := new(big.Int).Sub(a.x, x)
+	y.Mul(y, slope)
+	y.Sub(y, a.y)
+	y.Mod(y, secp256k1P)
+
+	return &point{x: x, y: y}
+}
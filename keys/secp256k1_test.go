@@ -0,0 +1,52 @@
+package keys
+
+import (
+	"asterizm/builder/networks"
+	"testing"
+)
+
+func TestDeriveEvm(t *testing.T) {
+	network, _ := networks.Lookup("ETH")
+
+	for privateKey, address := range map[string]string{
+		// the generator point and its double
+		"0x0000000000000000000000000000000000000000000000000000000000000001": "0x7E5F4552091A69125d5DfCb7b8C2659029395Bdf",
+		"0000000000000000000000000000000000000000000000000000000000000002":   "0x2B5AD5c4795c026514f8317c7a215E218DcCD6cF",
+		// the curve order minus one and the web3.js docs key
+		"0xfffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364140": "0x80C0dbf239224071c59dD8970ab9d542E3414aB2",
+		"0x4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318": "0x2c7536E3605D9C16a7a3D7b1898e529396a65c23",
+	} {
+		owner, err := Derive(network, privateKey, "", false)
+		if err != nil {
+			t.Errorf("%s: %v", privateKey, err)
+			continue
+		}
+
+		if owner.Address != address {
+			t.Errorf("%s address %s, want %s", privateKey, owner.Address, address)
+		}
+	}
+}
+
+func TestDeriveEvmRejects(t *testing.T) {
+	network, _ := networks.Lookup("ETH")
+
+	for _, privateKey := range []string{
+		"",
+		"0x01",
+		"not a key",
+		// zero and the curve order are out of range
+		"0x0000000000000000000000000000000000000000000000000000000000000000",
+		"0xfffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141",
+	} {
+		if _, err := Derive(network, privateKey, "", false); err == nil {
+			t.Errorf("%q is accepted", privateKey)
+		}
+	}
+}
+
+func TestSameAddressEvm(t *testing.T) {
+	if !SameAddress(networks.FamilyEVM, "0x7e5f4552091a69125d5dfcb7b8c2659029395bdf", "0x7E5F4552091A69125d5DfCb7b8C2659029395Bdf") {
+		t.Error("addresses of different case don't match")
+	}
+}
@@ -0,0 +1,214 @@
+package keys
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	tonMnemonicSalt      = "TON default seed"
+	tonMnemonicCheckSalt = "TON seed version"
+	tonMnemonicRounds    = 100000
+	// subwallet id of the v3/v4 wallets in the basic workchain
+	tonDefaultSubwallet = 698983191
+
+	tonMainnetGlobalId = -239
+	tonTestnetGlobalId = -3
+
+	tonBounceableTag    = 0x11
+	tonNonBounceableTag = 0x51
+	tonTestnetFlag      = 0x80
+)
+
+type tonWalletCode struct {
+	hash  string
+	depth uint16
+}
+
+// hashes and depths of the standard wallet code cells
+var tonWalletCodes = map[string]tonWalletCode{
+	"v3r1": {hash: "b61041a58a7980b946e8fb9e198e3c904d24799ffa36574ea4251c41a566f581", depth: 0},
+	"v3r2": {hash: "84dafa449f98a6987789ba232358072bc0f76dc4524002a5d0918b9a75d2d599", depth: 0},
+	"v4r1": {hash: "64dd54805522c5be8a9db59cea0105ccf0d08786ca79beb8cb79e880a8d7322d", depth: 7},
+	"v4r2": {hash: "feb5ff6820e2ff0d9483e7e0d62c817d846789fb4ae580c878866d959dabd5c0", depth: 7},
+	"v5r1": {hash: "20834b7b72b112147e1b2fb457b84e74d1a30f04f737d4f62a668e9552d2b72f", depth: 6},
+}
+
+// bitBuilder collects cell data bits
+type bitBuilder struct {
+	data []byte
+	bits int
+}
+
+func (b *bitBuilder) storeUint(value uint64, bits int) {
+	for i := bits - 1; i >= 0; i-- {
+		b.storeBit(value>>uint(i)&1 == 1)
+	}
+}
+
+func (b *bitBuilder) storeBytes(data []byte) {
+	for _, value := range data {
+		b.storeUint(uint64(value), 8)
+	}
+}
+
+func (b *bitBuilder) storeBit(bit bool) {
+	if b.bits%8 == 0 {
+		b.data = append(b.data, 0)
+	}
+
+	if bit {
+		b.data[len(b.data)-1] |= 0x80 >> uint(b.bits%8)
+	}
+	b.bits++
+}
+
+// cellHash returns representation hash of the ordinary cell
+func cellHash(b *bitBuilder, refDepths []uint16, refHashes [][]byte) []byte {
+	data := append([]byte(nil), b.data...)
+	if b.bits%8 != 0 {
+		data[len(data)-1] |= 0x80 >> uint(b.bits%8)
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte{byte(len(refHashes)), byte((b.bits+7)/8 + b.bits/8)})
+	hash.Write(data)
+	for _, depth := range refDepths {
+		hash.Write(binary.BigEndian.AppendUint16(nil, depth))
+	}
+	for _, refHash := range refHashes {
+		hash.Write(refHash)
+	}
+
+	return hash.Sum(nil)
+}
+
+// tonSeedFromMnemonic converts ton (not bip39) mnemonic to the ed25519 seed
+func tonSeedFromMnemonic(words []string) ([]byte, error) {
+	mac := hmac.New(sha512.New, []byte(strings.Join(words, " ")))
+	entropy := mac.Sum(nil)
+
+	if pbkdf2.Key(entropy, []byte(tonMnemonicCheckSalt), tonMnemonicRounds/256, 1, sha512.New)[0] != 0 {
+		return nil, errors.New("invalid ton mnemonic")
+	}
+
+	return pbkdf2.Key(entropy, []byte(tonMnemonicSalt), tonMnemonicRounds, 32, sha512.New), nil
+}
+
+// tonWalletAddress returns wallet address of the basic workchain
+func tonWalletAddress(publicKey []byte, walletType string, isTest bool) ([]byte, error) {
+	code, ok := tonWalletCodes[walletType]
+	if !ok {
+		return nil, fmt.Errorf("address derivation is not supported for %s wallets", walletType)
+	}
+
+	data := &bitBuilder{}
+	switch walletType {
+	case "v3r1", "v3r2":
+		data.storeUint(0, 32) // seqno
+		data.storeUint(tonDefaultSubwallet, 32)
+		data.storeBytes(publicKey)
+	case "v4r1", "v4r2":
+		data.storeUint(0, 32) // seqno
+		data.storeUint(tonDefaultSubwallet, 32)
+		data.storeBytes(publicKey)
+		data.storeBit(false) // empty plugins dict
+	case "v5r1":
+		globalId := int32(tonMainnetGlobalId)
+		if isTest {
+			globalId = tonTestnetGlobalId
+		}
+
+		// client context: 1 bit flag, workchain 0, version 0, subwallet 0
+		walletId := uint32(1<<31) ^ uint32(globalId)
+
+		data.storeBit(true)   // signature allowed
+		data.storeUint(0, 32) // seqno
+		data.storeUint(uint64(walletId), 32)
+		data.storeBytes(publicKey)
+		data.storeBit(false) // empty extensions dict
+	}
+
+	codeHash, _ := hex.DecodeString(code.hash)
+	dataHash := cellHash(data, nil, nil)
+
+	// state init: no split depth, no special, code, data, no library
+	stateInit := &bitBuilder{}
+	stateInit.storeUint(0b00110, 5)
+
+	return cellHash(stateInit, []uint16{code.depth, 0}, [][]byte{codeHash, dataHash}), nil
+}
+
+// formatTonAddress returns user-friendly address of the basic workchain
+func formatTonAddress(hash []byte, bounceable, isTest bool) string {
+	tag := byte(tonNonBounceableTag)
+	if bounceable {
+		tag = tonBounceableTag
+	}
+
+	if isTest {
+		tag |= tonTestnetFlag
+	}
+
+	data := append([]byte{tag, 0}, hash...)
+	data = binary.BigEndian.AppendUint16(data, crc16(data))
+
+	return base64.URLEncoding.EncodeToString(data)
+}
+
+// parseTonAddress accepts raw "0:hex" or user-friendly address
+// returns workchain and account hash
+func parseTonAddress(address string) (int8, []byte, error) {
+	if workchain, hash, ok := strings.Cut(address, ":"); ok {
+		wc, err := strconv.ParseInt(workchain, 10, 8)
+		if err != nil {
+			return 0, nil, fmt.Errorf("invalid workchain: %w", err)
+		}
+
+		decoded, err := hex.DecodeString(hash)
+		if err != nil || len(decoded) != 32 {
+			return 0, nil, errors.New("invalid raw address")
+		}
+
+		return int8(wc), decoded, nil
+	}
+
+	address = strings.NewReplacer("+", "-", "/", "_").Replace(address)
+	data, err := base64.URLEncoding.DecodeString(address)
+	if err != nil || len(data) != 36 {
+		return 0, nil, errors.New("invalid user-friendly address")
+	}
+
+	if binary.BigEndian.Uint16(data[34:]) != crc16(data[:34]) {
+		return 0, nil, errors.New("invalid address checksum")
+	}
+
+	return int8(data[1]), data[2:34], nil
+}
+
+// crc16 xmodem
+func crc16(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+
+	return crc
+}
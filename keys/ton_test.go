@@ -0,0 +1,81 @@
+package keys
+
+import (
+	"asterizm/builder/networks"
+	"encoding/hex"
+	"testing"
+)
+
+// the public key and the v3r2 address are published in tonutils-go ton/wallet/address_test.go,
+// the other addresses are computed by tonutils-go v1.12.0 wallet.AddressFromPubKey
+// with the default subwallet, 0 for v5r1
+const tonTestPublicKey = "dcc39550bb494f4b493e7efe1aa18ea31470f33a2553c568cb74a17ed56790c1"
+
+func TestTonWalletAddress(t *testing.T) {
+	publicKey, _ := hex.DecodeString(tonTestPublicKey)
+
+	for _, test := range []struct {
+		walletType     string
+		isTest         bool
+		bounceable     string
+		nonBounceable  string
+		testnetAddress string
+	}{
+		{"v3r2", false, "EQCvoBT5Keb46oUhI_DpX0WXFDdX9ZyxXBfX3FC9cZa90nQP", "UQCvoBT5Keb46oUhI_DpX0WXFDdX9ZyxXBfX3FC9cZa90inK", "0QCvoBT5Keb46oUhI_DpX0WXFDdX9ZyxXBfX3FC9cZa90pJA"},
+		{"v4r2", false, "EQAwwdowWbBKkrnRlbY8CUEzy_pgK9pIvOKP2eqcD01EWgBR", "UQAwwdowWbBKkrnRlbY8CUEzy_pgK9pIvOKP2eqcD01EWl2U", "0QAwwdowWbBKkrnRlbY8CUEzy_pgK9pIvOKP2eqcD01EWuYe"},
+		// v5r1 wallet id depends on the network global id
+		{"v5r1", false, "EQAg-EZkKvPFRi_kvrV7nCdQsIMnL8G08RUNB4eW5nbfO0k9", "UQAg-EZkKvPFRi_kvrV7nCdQsIMnL8G08RUNB4eW5nbfOxT4", ""},
+		{"v5r1", true, "EQA8Xz-JDmG1CRRsD0tYdRSR-MOweFgnUlFOuXuWGEu1a13h", "", "0QA8Xz-JDmG1CRRsD0tYdRSR-MOweFgnUlFOuXuWGEu1a7uu"},
+	} {
+		hash, err := tonWalletAddress(publicKey, test.walletType, test.isTest)
+		if err != nil {
+			t.Errorf("%s: %v", test.walletType, err)
+			continue
+		}
+
+		for address, got := range map[string]string{
+			test.bounceable:     formatTonAddress(hash, true, false),
+			test.nonBounceable:  formatTonAddress(hash, false, false),
+			test.testnetAddress: formatTonAddress(hash, false, true),
+		} {
+			if address != "" && got != address {
+				t.Errorf("%s (test %t) address %s, want %s", test.walletType, test.isTest, got, address)
+			}
+		}
+	}
+}
+
+func TestTonWalletAddressRejectsUnknownWallet(t *testing.T) {
+	publicKey, _ := hex.DecodeString(tonTestPublicKey)
+	if _, err := tonWalletAddress(publicKey, "highload", false); err == nil {
+		t.Error("unknown wallet type is accepted")
+	}
+}
+
+func TestParseTonAddress(t *testing.T) {
+	raw := "0:afa014f929e6f8ea852123f0e95f4597143757f59cb15c17d7dc50bd7196bdd2"
+
+	for _, address := range []string{
+		"EQCvoBT5Keb46oUhI_DpX0WXFDdX9ZyxXBfX3FC9cZa90nQP",
+		"UQCvoBT5Keb46oUhI_DpX0WXFDdX9ZyxXBfX3FC9cZa90inK",
+		"0QCvoBT5Keb46oUhI_DpX0WXFDdX9ZyxXBfX3FC9cZa90pJA",
+		// standard base64 alphabet
+		"EQCvoBT5Keb46oUhI/DpX0WXFDdX9ZyxXBfX3FC9cZa90nQP",
+	} {
+		if !SameAddress(networks.FamilyTON, raw, address) {
+			t.Errorf("%s doesn't match %s", address, raw)
+		}
+	}
+
+	for _, address := range []string{
+		"",
+		"0:afa0",
+		"x:afa014f929e6f8ea852123f0e95f4597143757f59cb15c17d7dc50bd7196bdd2",
+		// the last checksum byte is changed
+		"EQCvoBT5Keb46oUhI_DpX0WXFDdX9ZyxXBfX3FC9cZa90nQQ",
+	} {
+		if _, _, err := parseTonAddress(address); err == nil {
+			t.Errorf("%q is accepted", address)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package networks
+
+import (
+	"asterizm/builder/utils"
+	"sort"
+	"strings"
+)
+
+type Family string
+
+const (
+	FamilyEVM Family = "EVM"
+	FamilyTVM Family = "TVM"
+	FamilyTON Family = "TON"
+	FamilySOL Family = "SOL"
+)
+
+type Network struct {
+	Code   string
+	Family Family
+	// native coin decimals
+	Decimals int
+	// expected chain ids: decimal EVM chain id, TVM/TON global id or SOL genesis hash
+	// empty list means the id is not checked
+	MainnetChainIds []string
+	TestnetChainIds []string
+}
+
+// FamilyFields are config node fields applicable to the family
+var FamilyFields = map[Family][]string{
+	FamilyEVM: {"ContractAddress"},
+	FamilyTVM: {"ContractAddress", "OwnerPublicKey"},
+	FamilyTON: {"ContractAddress", "ArchiveRPC", "OwnerWalletType"},
+	FamilySOL: {
+		"TokenProgramId",
+		"TokenName",
+		"ClientProgramId",
+		"ClientUserAddress",
+		"InitializerProgramId",
+		"RelayerProgramId",
+		"SystemRelayOwnerAddress",
+		"RelayOwnerAddress",
+	},
+}
+
+// FamilyRequiredFields are mandatory config node fields of the family
+var FamilyRequiredFields = map[Family][]string{
+	FamilyEVM: {"ContractAddress"},
+	FamilyTVM: {"ContractAddress", "OwnerPublicKey"},
+	FamilyTON: {"ContractAddress", "ArchiveRPC", "OwnerWalletType"},
+	FamilySOL: {"TokenProgramId", "ClientProgramId", "InitializerProgramId", "RelayerProgramId"},
+}
+
+var TonWalletTypes = []string{"v3r1", "v3r2", "highloadv3", "v4r1", "v4r2", "v5r1"}
+
+var list = map[string]Network{
+	"ETH": evm("ETH", "1", "11155111", "17000"),
+	"POL": evm("POL", "137", "80002"),
+	"OPT": evm("OPT", "10", "11155420"),
+	"AUR": evm("AUR", "1313161554", "1313161555"),
+	"FTM": evm("FTM", "250", "4002"),
+	"CEL": evm("CEL", "42220", "44787"),
+	"AVA": evm("AVA", "43114", "43113"),
+	"ARB": evm("ARB", "42161", "421614"),
+	"BOB": evm("BOB", "60808", "808813"),
+	"BSC": evm("BSC", "56", "97"),
+	// chain ids of XVM and BTG deployments are not confirmed, a wrong id would fail every deploy, so they aren't checked
+	"XVM": {Code: "XVM", Family: FamilyEVM, Decimals: 18},
+	"PZK": evm("PZK", "1101", "2442"),
+	"BTG": {Code: "BTG", Family: FamilyEVM, Decimals: 18},
+	"EVER": {
+		Code:            "EVER",
+		Decimals:        9,
+		Family:          FamilyTVM,
+		MainnetChainIds: []string{"42"},
+	},
+	"VNM": {
+		Code:            "VNM",
+		Decimals:        9,
+		Family:          FamilyTVM,
+		MainnetChainIds: []string{"1000"},
+	},
+	"TON": {
+		Code:            "TON",
+		Decimals:        9,
+		Family:          FamilyTON,
+		MainnetChainIds: []string{"-239"},
+		TestnetChainIds: []string{"-3"},
+	},
+	"SOL": {
+		Code:            "SOL",
+		Decimals:        9,
+		Family:          FamilySOL,
+		MainnetChainIds: []string{"5eykt4UsFv8P8NJdTREpY1vzqKqZKvdpKuc147dw2N9d"},
+		TestnetChainIds: []string{
+			"EtWTRABZaYq6iMfeYKouRu166VU2xqa1wcaWoxPkrZBG", // devnet
+			"4uhcVJyU9pJkvQyS88uRDiswHXSCkY3zQawwpjk2NsNY", // testnet
+		},
+	},
+}
+
+// rpc url parts of well known test networks
+var testnetHints = []string{"testnet", "devnet", "sepolia", "goerli", "holesky", "amoy", "mumbai", "fuji", "alfajores", "cardona"}
+
+func evm(code, mainnetChainId string, testnetChainIds ...string) Network {
+	return Network{
+		Code:            code,
+		Family:          FamilyEVM,
+		Decimals:        18,
+		MainnetChainIds: []string{mainnetChainId},
+		TestnetChainIds: testnetChainIds,
+	}
+}
+
+// Lookup returns network by case-insensitive code
+func Lookup(code string) (Network, bool) {
+	network, ok := list[strings.ToUpper(code)]
+	return network, ok
+}
+
+// Codes returns sorted codes of all supported networks
+func Codes() []string {
+	codes := utils.MapKeys(list)
+	sort.Strings(codes)
+
+	return codes
+}
+
+// Suggest returns the closest supported network code
+func Suggest(code string) string {
+	return utils.ClosestString(strings.ToUpper(code), Codes())
+}
+
+// ChainIds returns expected chain ids for mainnet or testnet
+func (n Network) ChainIds(isTest bool) []string {
+	if isTest {
+		return n.TestnetChainIds
+	}
+
+	return n.MainnetChainIds
+}
+
+// Kind returns "mainnet" or "testnet" of the chain id, empty if the id is unknown
+func (n Network) Kind(chainId string) string {
+	switch {
+	case utils.InSlice(chainId, n.MainnetChainIds):
+		return "mainnet"
+	case utils.InSlice(chainId, n.TestnetChainIds):
+		return "testnet"
+	}
+
+	return ""
+}
+
+// IsApplicable checks that the node field is used by the network family
+func (n Network) IsApplicable(field string) bool {
+	return utils.InSlice(field, FamilyFields[n.Family])
+}
+
+// IsRequired checks that the node field is mandatory for the network family
+func (n Network) IsRequired(field string) bool {
+	return utils.InSlice(field, FamilyRequiredFields[n.Family])
+}
+
+// LooksLikeTestnet checks rpc url for well known test network names
+func LooksLikeTestnet(rpc string) bool {
+	rpc = strings.ToLower(rpc)
+	for _, hint := range testnetHints {
+		if strings.Contains(rpc, hint) {
+			return true
+		}
+	}
+
+	return false
+}
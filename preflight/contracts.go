@@ -0,0 +1,100 @@
+package preflight
+
+import (
+	"asterizm/builder/config"
+	"asterizm/builder/networks"
+	"asterizm/builder/utils"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CheckContracts checks that client contracts (EVM ContractAddress, SOL ClientProgramId) are deployed
+// other families are skipped
+func (p *Prober) CheckContracts(ctx context.Context, cfg *config.Config) []Result {
+	type target struct {
+		network networks.Network
+		node    config.Node
+	}
+
+	var targets []target
+	for _, key := range utils.SortedKeys(cfg.Nodes.List) {
+		network, ok := networks.Lookup(key)
+		if !ok || (network.Family != networks.FamilyEVM && network.Family != networks.FamilySOL) {
+			continue
+		}
+
+		targets = append(targets, target{network: network, node: cfg.Nodes.List[key]})
+	}
+
+	return runConcurrently(len(targets), func(i int) Result {
+		return p.CheckContract(ctx, targets[i].network, targets[i].node)
+	})
+}
+
+// CheckContract checks that the node client contract exists through the node RPC
+func (p *Prober) CheckContract(ctx context.Context, network networks.Network, node config.Node) Result {
+	result := Result{Network: network.Code}
+
+	start := time.Now()
+	switch network.Family {
+	case networks.FamilyEVM:
+		result.Endpoint = "ContractAddress"
+		result.Details, result.Err = p.checkEvmContract(ctx, node)
+	case networks.FamilySOL:
+		result.Endpoint = "ClientProgramId"
+		result.Details, result.Err = p.checkSolProgram(ctx, node)
+	default:
+		result.Err = fmt.Errorf("contract check is not supported for %s networks", network.Family)
+	}
+	result.Latency = time.Since(start)
+
+	return result
+}
+
+func (p *Prober) checkEvmContract(ctx context.Context, node config.Node) (string, error) {
+	if node.ContractAddress == nil || *node.ContractAddress == "" {
+		return "", fmt.Errorf("empty contract address")
+	}
+
+	var code string
+	if err := p.callRpc(ctx, node.RPC, "eth_getCode", []any{*node.ContractAddress, "latest"}, &code); err != nil {
+		return "", err
+	}
+
+	code = strings.TrimPrefix(code, "0x")
+	if code == "" {
+		return "", fmt.Errorf("no contract deployed at %s, check that it belongs to this network", *node.ContractAddress)
+	}
+
+	return fmt.Sprintf("%d bytes of code", len(code)/2), nil
+}
+
+func (p *Prober) checkSolProgram(ctx context.Context, node config.Node) (string, error) {
+	if node.ClientProgramId == nil || *node.ClientProgramId == "" {
+		return "", fmt.Errorf("empty client program id")
+	}
+
+	info := &struct {
+		Value *struct {
+			Executable bool   `json:"executable"`
+			Owner      string `json:"owner"`
+		} `json:"value"`
+	}{}
+
+	params := []any{*node.ClientProgramId, map[string]string{"encoding": "base64"}}
+	if err := p.callRpc(ctx, node.RPC, "getAccountInfo", params, info); err != nil {
+		return "", err
+	}
+
+	if info.Value == nil {
+		return "", fmt.Errorf("no account %s, check that it belongs to this network", *node.ClientProgramId)
+	}
+
+	if !info.Value.Executable {
+		return "", fmt.Errorf("account %s is not a program", *node.ClientProgramId)
+	}
+
+	return "program owned by " + info.Value.Owner, nil
+}
@@ -0,0 +1,194 @@
+package preflight
+
+import (
+	"asterizm/builder/config"
+	"asterizm/builder/keys"
+	"asterizm/builder/networks"
+	"asterizm/builder/utils"
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// CheckOwners derives owner addresses from OwnerPrivateKey, compares them with OwnerAddress
+// and warns about owner balances lower than MinOwnerBalance
+func (p *Prober) CheckOwners(ctx context.Context, cfg *config.Config) []Result {
+	type target struct {
+		network networks.Network
+		node    config.Node
+	}
+
+	var (
+		targets []target
+		results []Result
+	)
+	for _, key := range utils.SortedKeys(cfg.Nodes.List) {
+		node := cfg.Nodes.List[key]
+		network, ok := networks.Lookup(key)
+		if !ok || node.OwnerPrivateKey == nil || *node.OwnerPrivateKey == "" {
+			continue
+		}
+
+		privateKey, err := cfg.DecryptOwnerKey(key)
+		if err != nil {
+			results = append(results, Result{Network: network.Code, Endpoint: "Owner", Err: err})
+			continue
+		}
+
+		node.OwnerPrivateKey = &privateKey
+		targets = append(targets, target{network: network, node: node})
+	}
+
+	return append(results, runConcurrently(len(targets), func(i int) Result {
+		return p.CheckOwner(ctx, targets[i].network, targets[i].node)
+	})...)
+}
+
+// CheckOwner checks plaintext owner key of the node and its balance through the node RPC
+func (p *Prober) CheckOwner(ctx context.Context, network networks.Network, node config.Node) Result {
+	result := Result{Network: network.Code, Endpoint: "Owner"}
+
+	walletType := ""
+	if node.OwnerWalletType != nil {
+		walletType = *node.OwnerWalletType
+	}
+
+	owner, err := keys.Derive(network, *node.OwnerPrivateKey, walletType, p.isTest)
+	if err != nil {
+		result.Err = fmt.Errorf("invalid OwnerPrivateKey: %w", err)
+		return result
+	}
+
+	if err := checkOwner(network, node, walletType, owner); err != nil {
+		result.Err = err
+		return result
+	}
+
+	if owner.Address == "" {
+		result.Details = "public key " + owner.PublicKey
+		return result
+	}
+
+	result.Details = owner.Address
+
+	start := time.Now()
+	balance, err := p.balance(ctx, network, node.RPC, owner.Address)
+	result.Latency = time.Since(start)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	result.Details += ", balance " + formatAmount(balance, network.Decimals)
+
+	minBalance := new(big.Int)
+	if node.MinOwnerBalance != nil && *node.MinOwnerBalance != "" {
+		if minBalance, err = parseAmount(*node.MinOwnerBalance, network.Decimals); err != nil {
+			result.Err = fmt.Errorf("invalid MinOwnerBalance: %w", err)
+			return result
+		}
+	}
+
+	if minBalance.Sign() == 0 && balance.Sign() == 0 {
+		result.Warning = "owner wallet is empty"
+	} else if balance.Cmp(minBalance) < 0 {
+		result.Warning = fmt.Sprintf("owner balance is lower than %s", formatAmount(minBalance, network.Decimals))
+	}
+
+	return result
+}
+
+// checkOwner compares derived owner with OwnerAddress and TVM OwnerPublicKey
+func checkOwner(network networks.Network, node config.Node, walletType string, owner *keys.Owner) error {
+	if node.OwnerAddress != nil && *node.OwnerAddress != "" {
+		if owner.Address == "" {
+			return fmt.Errorf("OwnerAddress can't be checked for %s networks or %q wallets", network.Family, walletType)
+		}
+
+		if !keys.SameAddress(network.Family, owner.Address, *node.OwnerAddress) {
+			return fmt.Errorf("OwnerPrivateKey belongs to %s, but OwnerAddress is %s", owner.Address, *node.OwnerAddress)
+		}
+	}
+
+	if network.Family == networks.FamilyTVM && node.OwnerPublicKey != nil && *node.OwnerPublicKey != "" {
+		if !strings.EqualFold(strings.TrimPrefix(*node.OwnerPublicKey, "0x"), owner.PublicKey) {
+			return fmt.Errorf("OwnerPrivateKey public key is %s, but OwnerPublicKey is %s", owner.PublicKey, *node.OwnerPublicKey)
+		}
+	}
+
+	return nil
+}
+
+// balance returns native balance in the smallest units
+func (p *Prober) balance(ctx context.Context, network networks.Network, url, address string) (*big.Int, error) {
+	switch network.Family {
+	case networks.FamilyEVM:
+		var balanceHex string
+		if err := p.callRpc(ctx, url, "eth_getBalance", []any{address, "latest"}, &balanceHex); err != nil {
+			return nil, err
+		}
+
+		balance, ok := new(big.Int).SetString(strings.TrimPrefix(balanceHex, "0x"), 16)
+		if !ok {
+			return nil, fmt.Errorf("eth_getBalance: invalid balance %q", balanceHex)
+		}
+
+		return balance, nil
+	case networks.FamilySOL:
+		data := &struct {
+			Value uint64 `json:"value"`
+		}{}
+
+		if err := p.callRpc(ctx, url, "getBalance", []any{address}, data); err != nil {
+			return nil, err
+		}
+
+		return new(big.Int).SetUint64(data.Value), nil
+	case networks.FamilyTON:
+		var balanceString string
+		if err := p.callRpc(ctx, url, "getAddressBalance", map[string]any{"address": address}, &balanceString); err != nil {
+			return nil, err
+		}
+
+		balance, ok := new(big.Int).SetString(balanceString, 10)
+		if !ok {
+			return nil, fmt.Errorf("getAddressBalance: invalid balance %q", balanceString)
+		}
+
+		return balance, nil
+	}
+
+	return nil, fmt.Errorf("balance check is not supported for %s networks", network.Family)
+}
+
+// parseAmount converts decimal amount of native coins to the smallest units
+func parseAmount(amount string, decimals int) (*big.Int, error) {
+	whole, fraction, _ := strings.Cut(strings.TrimSpace(amount), ".")
+	if len(fraction) > decimals {
+		return nil, fmt.Errorf("%q has more than %d decimals", amount, decimals)
+	}
+
+	value, ok := new(big.Int).SetString(whole+fraction+strings.Repeat("0", decimals-len(fraction)), 10)
+	if !ok || value.Sign() < 0 {
+		return nil, fmt.Errorf("%q is not a positive decimal number", amount)
+	}
+
+	return value, nil
+}
+
+// formatAmount converts the smallest units to decimal amount of native coins
+func formatAmount(value *big.Int, decimals int) string {
+	digits := value.String()
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+
+	whole, fraction := digits[:len(digits)-decimals], strings.TrimRight(digits[len(digits)-decimals:], "0")
+	if fraction == "" {
+		return whole
+	}
+
+	return whole + "." + fraction
+}
@@ -0,0 +1,244 @@
+package preflight
+
+import (
+	"asterizm/builder/config"
+	"asterizm/builder/networks"
+	"asterizm/builder/utils"
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	DefaultTimeout  = 10 * time.Second
+	maxResponseSize = 10 << 20
+)
+
+// Result is a single endpoint check
+type Result struct {
+	Network  string
+	Endpoint string
+	Latency  time.Duration
+	// chain id reported by the endpoint: EVM chain id or SOL genesis hash, empty if not queried
+	ChainId string
+	Details string
+	// doesn't fail the preflight
+	Warning string
+	Err     error
+}
+
+type Prober struct {
+	client *http.Client
+	isTest bool
+}
+
+func NewProber(client *http.Client, isTest bool) *Prober {
+	if client == nil {
+		client = &http.Client{Timeout: DefaultTimeout}
+	}
+
+	return &Prober{
+		client: client,
+		isTest: isTest,
+	}
+}
+
+// ProbeConfig checks RPC (and TON ArchiveRPC) of every configured network concurrently
+func (p *Prober) ProbeConfig(ctx context.Context, cfg *config.Config) []Result {
+	type target struct {
+		network  networks.Network
+		endpoint string
+		url      string
+	}
+
+	var targets []target
+	for _, key := range utils.SortedKeys(cfg.Nodes.List) {
+		node := cfg.Nodes.List[key]
+		network, ok := networks.Lookup(key)
+		if !ok {
+			continue
+		}
+
+		targets = append(targets, target{network: network, endpoint: "RPC", url: node.RPC})
+		if network.Family == networks.FamilyTON && node.ArchiveRpc != nil && *node.ArchiveRpc != "" {
+			targets = append(targets, target{network: network, endpoint: "ArchiveRPC", url: *node.ArchiveRpc})
+		}
+	}
+
+	return runConcurrently(len(targets), func(i int) Result {
+		result := p.Probe(ctx, targets[i].network, targets[i].url)
+		result.Endpoint = targets[i].endpoint
+		return result
+	})
+}
+
+// Probe checks that the url responds correctly for the network family
+func (p *Prober) Probe(ctx context.Context, network networks.Network, url string) Result {
+	result := Result{Network: network.Code, Endpoint: "RPC"}
+
+	start := time.Now()
+	switch network.Family {
+	case networks.FamilyEVM:
+		result.ChainId, result.Details, result.Err = p.probeEvm(ctx, network, url)
+	case networks.FamilySOL:
+		result.ChainId, result.Details, result.Err = p.probeSol(ctx, network, url)
+	case networks.FamilyTON:
+		result.Details, result.Err = p.probeTon(ctx, url)
+	case networks.FamilyTVM:
+		result.Details, result.Err = p.probeTvm(ctx, url)
+	default:
+		result.Err = fmt.Errorf("unsupported network family %s", network.Family)
+	}
+	result.Latency = time.Since(start)
+
+	return result
+}
+
+// probeEvm returns the chain id and details of the EVM node
+func (p *Prober) probeEvm(ctx context.Context, network networks.Network, url string) (string, string, error) {
+	var chainIdHex string
+	if err := p.callRpc(ctx, url, "eth_chainId", nil, &chainIdHex); err != nil {
+		return "", "", err
+	}
+
+	chainId, ok := new(big.Int).SetString(strings.TrimPrefix(chainIdHex, "0x"), 16)
+	if !ok {
+		return "", "", fmt.Errorf("eth_chainId: invalid chain id %q", chainIdHex)
+	}
+
+	details := "chain id " + chainId.String()
+	return chainId.String(), details, p.checkChainId(network, chainId.String())
+}
+
+// probeSol returns the genesis hash and details of the Solana node
+func (p *Prober) probeSol(ctx context.Context, network networks.Network, url string) (string, string, error) {
+	var health string
+	if err := p.callRpc(ctx, url, "getHealth", nil, &health); err != nil {
+		return "", "", err
+	}
+
+	if health != "ok" {
+		return "", "", fmt.Errorf("getHealth: node is %s", health)
+	}
+
+	var genesisHash string
+	if err := p.callRpc(ctx, url, "getGenesisHash", nil, &genesisHash); err != nil {
+		return "", "", err
+	}
+
+	details := "healthy, genesis " + genesisHash
+	return genesisHash, details, p.checkChainId(network, genesisHash)
+}
+
+func (p *Prober) probeTon(ctx context.Context, url string) (string, error) {
+	info := &struct {
+		Last struct {
+			Seqno uint64 `json:"seqno"`
+		} `json:"last"`
+	}{}
+
+	if err := p.callRpc(ctx, url, "getMasterchainInfo", map[string]any{}, info); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("masterchain seqno %d", info.Last.Seqno), nil
+}
+
+func (p *Prober) probeTvm(ctx context.Context, url string) (string, error) {
+	data := &struct {
+		Info struct {
+			Version string `json:"version"`
+		} `json:"info"`
+	}{}
+
+	if err := p.callGraphql(ctx, url, "{info{version}}", data); err != nil {
+		return "", err
+	}
+
+	return "graphql version " + data.Info.Version, nil
+}
+
+// checkChainId compares chain id with the registry expectation for the selected mainnet or testnet
+func (p *Prober) checkChainId(network networks.Network, chainId string) error {
+	expected := network.ChainIds(p.isTest)
+	if len(expected) == 0 || utils.InSlice(chainId, expected) {
+		return nil
+	}
+
+	kind, otherKind := "mainnet", "testnet"
+	if p.isTest {
+		kind, otherKind = otherKind, kind
+	}
+
+	if utils.InSlice(chainId, network.ChainIds(!p.isTest)) {
+		return fmt.Errorf("%s is a %s rpc, but %s is expected", chainId, otherKind, kind)
+	}
+
+	return fmt.Errorf("unexpected chain id %s, %s expects %s", chainId, kind, strings.Join(expected, " or "))
+}
+
+// runConcurrently runs checks in parallel and keeps results order
+func runConcurrently(count int, check func(i int) Result) []Result {
+	results := make([]Result, count)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = check(i)
+		}(i)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// ChainIds returns chain ids reported by RPC endpoints of the networks
+func ChainIds(results []Result) map[string]string {
+	chainIds := make(map[string]string)
+	for _, result := range results {
+		if result.Endpoint == "RPC" && result.ChainId != "" {
+			chainIds[result.Network] = result.ChainId
+		}
+	}
+
+	return chainIds
+}
+
+// Failed returns results with errors
+func Failed(results []Result) []Result {
+	var failed []Result
+	for _, result := range results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+
+	return failed
+}
+
+// PrintTable writes results as a per-network table
+func PrintTable(w io.Writer, results []Result) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NETWORK\tENDPOINT\tSTATUS\tLATENCY\tDETAILS")
+
+	for _, result := range results {
+		status, details := "OK", result.Details
+		if result.Err != nil {
+			status, details = "FAIL", result.Err.Error()
+		} else if result.Warning != "" {
+			status, details = "WARN", strings.TrimPrefix(details+", "+result.Warning, ", ")
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", result.Network, result.Endpoint, status, result.Latency.Round(time.Millisecond), details)
+	}
+
+	tw.Flush()
+}
@@ -0,0 +1,202 @@
+package preflight
+
+import (
+	"asterizm/builder/networks"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rpcServer is a stand-in node which answers json-rpc methods with the raw result values
+func rpcServer(t *testing.T, results map[string]string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := rpcRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		result, ok := results[request.Method]
+		if !ok {
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"method not found"}}`))
+			return
+		}
+
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":` + result + `}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func lookupNetwork(t *testing.T, code string) networks.Network {
+	t.Helper()
+
+	network, ok := networks.Lookup(code)
+	if !ok {
+		t.Fatalf("unknown network %s", code)
+	}
+
+	return network
+}
+
+func TestProbeEvmChainId(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		chainId string
+		isTest  bool
+		err     string
+	}{
+		{name: "mainnet", chainId: `"0x1"`},
+		{name: "testnet", chainId: `"0xaa36a7"`, isTest: true},
+		{name: "testnet rpc for mainnet", chainId: `"0xaa36a7"`, err: "11155111 is a testnet rpc, but mainnet is expected"},
+		{name: "mainnet rpc for testnet", chainId: `"0x1"`, isTest: true, err: "1 is a mainnet rpc, but testnet is expected"},
+		{name: "another chain", chainId: `"0x38"`, err: "unexpected chain id 56"},
+		{name: "invalid chain id", chainId: `"0xzz"`, err: "invalid chain id"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			server := rpcServer(t, map[string]string{"eth_chainId": test.chainId})
+
+			result := NewProber(nil, test.isTest).Probe(context.Background(), lookupNetwork(t, "ETH"), server.URL)
+			checkResult(t, result, test.err)
+
+			if test.err == "" && ChainIds([]Result{result})["ETH"] == "" {
+				t.Error("chain id is not reported")
+			}
+		})
+	}
+}
+
+func TestProbeSol(t *testing.T) {
+	mainnet := `"5eykt4UsFv8P8NJdTREpY1vzqKqZKvdpKuc147dw2N9d"`
+	devnet := `"EtWTRABZaYq6iMfeYKouRu166VU2xqa1wcaWoxPkrZBG"`
+
+	for _, test := range []struct {
+		name    string
+		health  string
+		genesis string
+		isTest  bool
+		err     string
+	}{
+		{name: "mainnet", health: `"ok"`, genesis: mainnet},
+		{name: "devnet", health: `"ok"`, genesis: devnet, isTest: true},
+		{name: "devnet rpc for mainnet", health: `"ok"`, genesis: devnet, err: "is a testnet rpc"},
+		{name: "unhealthy", health: `"behind"`, genesis: mainnet, err: "getHealth: node is behind"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			server := rpcServer(t, map[string]string{"getHealth": test.health, "getGenesisHash": test.genesis})
+
+			result := NewProber(nil, test.isTest).Probe(context.Background(), lookupNetwork(t, "SOL"), server.URL)
+			checkResult(t, result, test.err)
+
+			if test.err == "" && ChainIds([]Result{result})["SOL"] == "" {
+				t.Error("chain id is not reported")
+			}
+		})
+	}
+}
+
+func TestProbeTon(t *testing.T) {
+	server := rpcServer(t, map[string]string{"getMasterchainInfo": `{"last":{"seqno":42}}`})
+
+	result := NewProber(nil, false).Probe(context.Background(), lookupNetwork(t, "TON"), server.URL)
+	checkResult(t, result, "")
+
+	if result.Details != "masterchain seqno 42" {
+		t.Errorf("details %q", result.Details)
+	}
+}
+
+func TestProbeTvm(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := map[string]string{}
+		json.NewDecoder(r.Body).Decode(&request)
+
+		if request["query"] != "{info{version}}" {
+			w.Write([]byte(`{"errors":[{"message":"unknown query"}]}`))
+			return
+		}
+
+		w.Write([]byte(`{"data":{"info":{"version":"0.67.0"}}}`))
+	}))
+	defer server.Close()
+
+	result := NewProber(nil, false).Probe(context.Background(), lookupNetwork(t, "EVER"), server.URL)
+	checkResult(t, result, "")
+
+	if result.Details != "graphql version 0.67.0" {
+		t.Errorf("details %q", result.Details)
+	}
+}
+
+func TestProbeHttpStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	result := NewProber(nil, false).Probe(context.Background(), lookupNetwork(t, "ETH"), server.URL)
+	checkResult(t, result, "http status 503")
+}
+
+func TestProbeRpcError(t *testing.T) {
+	// eth_chainId isn't answered, the server returns json-rpc error with 200 status
+	server := rpcServer(t, map[string]string{})
+
+	result := NewProber(nil, false).Probe(context.Background(), lookupNetwork(t, "ETH"), server.URL)
+	checkResult(t, result, "eth_chainId: rpc error")
+
+	if !strings.Contains(result.Err.Error(), "method not found") {
+		t.Errorf("error %q doesn't contain the rpc message", result.Err)
+	}
+}
+
+func TestProbeTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	start := time.Now()
+	result := NewProber(&http.Client{Timeout: 100 * time.Millisecond}, false).Probe(context.Background(), lookupNetwork(t, "ETH"), server.URL)
+	checkResult(t, result, "eth_chainId: post request")
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("probe took %s", elapsed)
+	}
+
+	// the url may contain api keys
+	if strings.Contains(result.Err.Error(), server.URL) {
+		t.Errorf("error %q contains the url", result.Err)
+	}
+}
+
+func checkResult(t *testing.T, result Result, expectedErr string) {
+	t.Helper()
+
+	if expectedErr == "" {
+		if result.Err != nil {
+			t.Fatalf("unexpected error: %v", result.Err)
+		}
+		return
+	}
+
+	if result.Err == nil {
+		t.Fatalf("no error, details %q, want %q", result.Details, expectedErr)
+	}
+
+	if !strings.Contains(result.Err.Error(), expectedErr) {
+		t.Fatalf("error %q, want %q", result.Err, expectedErr)
+	}
+}
@@ -0,0 +1,123 @@
+package preflight
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"strings"
+)
+
+type rpcRequest struct {
+	JsonRpc string `json:"jsonrpc"`
+	Id      int    `json:"id"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  json.RawMessage `json:"error"`
+}
+
+type graphqlResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// callRpc sends json-rpc 2.0 request and decodes its result
+func (p *Prober) callRpc(ctx context.Context, url, method string, params any, result any) error {
+	if params == nil {
+		params = []any{}
+	}
+
+	response := &rpcResponse{}
+	err := p.post(ctx, url, rpcRequest{JsonRpc: "2.0", Id: 1, Method: method, Params: params}, response)
+
+	if len(response.Error) > 0 && string(response.Error) != "null" {
+		return fmt.Errorf("%s: rpc error %s", method, response.Error)
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+
+	if len(response.Result) == 0 {
+		return fmt.Errorf("%s: empty result", method)
+	}
+
+	if err := json.Unmarshal(response.Result, result); err != nil {
+		return fmt.Errorf("%s: decode result: %w", method, err)
+	}
+
+	return nil
+}
+
+// callGraphql sends graphql query and decodes its data
+func (p *Prober) callGraphql(ctx context.Context, url, query string, result any) error {
+	response := &graphqlResponse{}
+	err := p.post(ctx, url, map[string]string{"query": query}, response)
+
+	if len(response.Errors) > 0 {
+		messages := make([]string, len(response.Errors))
+		for i, e := range response.Errors {
+			messages[i] = e.Message
+		}
+		return fmt.Errorf("graphql error: %s", strings.Join(messages, "; "))
+	}
+
+	if err != nil {
+		return fmt.Errorf("graphql: %w", err)
+	}
+
+	if err := json.Unmarshal(response.Data, result); err != nil {
+		return fmt.Errorf("graphql: decode data: %w", err)
+	}
+
+	return nil
+}
+
+func (p *Prober) post(ctx context.Context, url string, body any, result any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		// url may contain api keys, so it is not printed
+		if urlErr, ok := err.(*neturl.Error); ok {
+			return fmt.Errorf("%s request: %w", strings.ToLower(urlErr.Op), urlErr.Err)
+		}
+		return err
+	}
+	defer response.Body.Close()
+
+	responseData, err := io.ReadAll(io.LimitReader(response.Body, maxResponseSize))
+	if err != nil {
+		return err
+	}
+
+	// rpc errors may come with non 2xx status, so the body is decoded anyway
+	decodeErr := json.Unmarshal(responseData, result)
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("http status %d", response.StatusCode)
+	}
+
+	if decodeErr != nil {
+		return fmt.Errorf("invalid json response: %w", decodeErr)
+	}
+
+	return nil
+}
@@ -5,5 +5,5 @@ import _ "embed"
 //go:embed install-docker.sh
 var InstallDocker string
 
-//go:embed init-docker-compose.sh
-var InitDockerCompose string
+//go:embed init-state-dir.sh
+var InitStateDir string
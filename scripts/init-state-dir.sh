@@ -0,0 +1,17 @@
+#!/bin/bash
+set -e
+
+stateDir=$1
+if [ $SUDO_USER ]; then user=$SUDO_USER; else user=`whoami`; fi
+
+if [[ ! -d $stateDir ]]; then
+    mkdir -p $stateDir
+    chown "$user:$user" $stateDir
+fi
+
+for file in docker-compose.yml config.yml; do
+    if [[ ! -e $stateDir/$file ]]; then
+        touch $stateDir/$file
+        chown "$user:$user" $stateDir/$file
+    fi
+done
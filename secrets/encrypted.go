@@ -0,0 +1,31 @@
+package secrets
+
+import (
+	"asterizm/builder/utils"
+	"context"
+	"errors"
+)
+
+// EncryptedProvider decrypts inline values encrypted with Utils.Encryption settings
+type EncryptedProvider struct {
+	// encryptor is taken on use, the encryption settings may be resolved or generated later
+	Encryptor func() *utils.Encryptor
+}
+
+func NewEncryptedProvider(encryptor func() *utils.Encryptor) *EncryptedProvider {
+	return &EncryptedProvider{Encryptor: encryptor}
+}
+
+func (p *EncryptedProvider) Resolve(_ context.Context, reference string) (string, error) {
+	encryptor := p.Encryptor()
+	if encryptor == nil {
+		return "", errors.New("encryption settings are not available")
+	}
+
+	plaintext, err := encryptor.Decrypt([]byte(reference), "", "")
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
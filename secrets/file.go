@@ -0,0 +1,35 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// FileProvider reads secrets from files, relative paths are resolved from the base dir
+type FileProvider struct {
+	BaseDir string
+}
+
+func NewFileProvider(baseDir string) *FileProvider {
+	return &FileProvider{BaseDir: baseDir}
+}
+
+func (p *FileProvider) Resolve(_ context.Context, reference string) (string, error) {
+	data, err := os.ReadFile(resolvePath(p.BaseDir, reference))
+	if err != nil {
+		return "", fmt.Errorf("read %s: %w", reference, err)
+	}
+
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+func resolvePath(baseDir, name string) string {
+	if path.IsAbs(name) {
+		return name
+	}
+
+	return path.Join(baseDir, name)
+}
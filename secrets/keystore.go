@@ -0,0 +1,198 @@
+package secrets
+
+import (
+	"asterizm/builder/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"golang.org/x/term"
+)
+
+const (
+	// KeystorePassphraseEnv is used instead of the terminal prompt
+	KeystorePassphraseEnv = "ASTERIZM_KEYSTORE_PASSPHRASE"
+
+	keystoreVersion = 1
+	keystoreCheck   = "asterizm keystore"
+)
+
+// Keystore is a file of named secrets encrypted with a passphrase
+type Keystore struct {
+	Version int    `json:"version"`
+	Salt    string `json:"salt"`
+	// encrypted known value, detects a wrong passphrase
+	Check   string            `json:"check"`
+	Secrets map[string]string `json:"secrets"`
+
+	path      string
+	encryptor *utils.Encryptor
+}
+
+// OpenKeystore reads and unlocks the keystore, a new one is created if the file doesn't exist and create is set
+func OpenKeystore(path, passphrase string, create bool) (*Keystore, error) {
+	if passphrase == "" {
+		return nil, errors.New("empty keystore passphrase")
+	}
+
+	keystore := &Keystore{path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && create {
+		salt, err := utils.GenerateEncryptionString(32)
+		if err != nil {
+			return nil, fmt.Errorf("generate keystore salt: %w", err)
+		}
+
+		keystore.Version = keystoreVersion
+		keystore.Salt = salt
+		keystore.Secrets = make(map[string]string)
+		keystore.encryptor = keystoreEncryptor(passphrase, salt)
+
+		check, err := keystore.encryptor.Encrypt([]byte(keystoreCheck), "", "")
+		if err != nil {
+			return nil, err
+		}
+		keystore.Check = string(check)
+
+		return keystore, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("read keystore: %w", err)
+	}
+
+	if err := json.Unmarshal(data, keystore); err != nil {
+		return nil, fmt.Errorf("parse keystore %s: %w", path, err)
+	}
+
+	if keystore.Version != keystoreVersion {
+		return nil, fmt.Errorf("unsupported keystore version %d", keystore.Version)
+	}
+
+	if keystore.Secrets == nil {
+		keystore.Secrets = make(map[string]string)
+	}
+
+	keystore.encryptor = keystoreEncryptor(passphrase, keystore.Salt)
+	if check, err := keystore.encryptor.Decrypt([]byte(keystore.Check), "", ""); err != nil || string(check) != keystoreCheck {
+		return nil, fmt.Errorf("wrong passphrase of the keystore %s", path)
+	}
+
+	return keystore, nil
+}
+
+// Get returns decrypted secret
+func (k *Keystore) Get(name string) (string, error) {
+	encrypted, ok := k.Secrets[name]
+	if !ok {
+		return "", fmt.Errorf("no secret %q in the keystore %s", name, k.path)
+	}
+
+	plaintext, err := k.encryptor.Decrypt([]byte(encrypted), "", "")
+	if err != nil {
+		return "", fmt.Errorf("decrypt secret %q: %w", name, err)
+	}
+
+	return string(plaintext), nil
+}
+
+// Set encrypts and stores the secret, call Save to write the keystore
+func (k *Keystore) Set(name, secret string) error {
+	encrypted, err := k.encryptor.Encrypt([]byte(secret), "", "")
+	if err != nil {
+		return fmt.Errorf("encrypt secret %q: %w", name, err)
+	}
+
+	k.Secrets[name] = string(encrypted)
+	return nil
+}
+
+// Names returns sorted secret names
+func (k *Keystore) Names() []string {
+	names := utils.MapKeys(k.Secrets)
+	sort.Strings(names)
+
+	return names
+}
+
+// Save writes the keystore readable only by its owner
+func (k *Keystore) Save() error {
+	data, err := json.MarshalIndent(k, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := utils.WriteFileAtomic(k.path, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("write keystore: %w", err)
+	}
+
+	return os.Chmod(k.path, 0600)
+}
+
+func keystoreEncryptor(passphrase, salt string) *utils.Encryptor {
+	return utils.NewEncryptor(passphrase, salt, "AES-256-GCM").WithKdf(utils.Kdf{Algorithm: utils.KdfArgon2id})
+}
+
+// KeystorePassphrase returns passphrase from the environment or the terminal prompt
+func KeystorePassphrase(path string) (string, error) {
+	if passphrase, ok := os.LookupEnv(KeystorePassphraseEnv); ok {
+		return passphrase, nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("please, set %s to unlock the keystore %s", KeystorePassphraseEnv, path)
+	}
+
+	fmt.Fprintf(os.Stderr, "Passphrase of the keystore %s: ", path)
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("read passphrase: %w", err)
+	}
+
+	return string(passphrase), nil
+}
+
+// KeystoreProvider resolves "path/to/keystore.json#name" references
+type KeystoreProvider struct {
+	BaseDir    string
+	Passphrase func(path string) (string, error)
+
+	keystores map[string]*Keystore
+}
+
+func NewKeystoreProvider(baseDir string) *KeystoreProvider {
+	return &KeystoreProvider{
+		BaseDir:    baseDir,
+		Passphrase: KeystorePassphrase,
+		keystores:  make(map[string]*Keystore),
+	}
+}
+
+func (p *KeystoreProvider) Resolve(_ context.Context, reference string) (string, error) {
+	location, name, err := splitField(reference)
+	if err != nil {
+		return "", err
+	}
+
+	path := resolvePath(p.BaseDir, location)
+	keystore, ok := p.keystores[path]
+	if !ok {
+		passphrase, err := p.Passphrase(path)
+		if err != nil {
+			return "", err
+		}
+
+		if keystore, err = OpenKeystore(path, passphrase, false); err != nil {
+			return "", err
+		}
+		p.keystores[path] = keystore
+	}
+
+	return keystore.Get(name)
+}
@@ -0,0 +1,138 @@
+package secrets
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestKeystoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "owners.json")
+
+	keystore, err := OpenKeystore(path, "passphrase", true)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	if err := keystore.Set("eth_owner", "0xabc"); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+
+	if err := keystore.Save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("keystore mode %o", info.Mode().Perm())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(data), "0xabc") {
+		t.Error("keystore contains the plaintext secret")
+	}
+
+	reopened, err := OpenKeystore(path, "passphrase", false)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	secret, err := reopened.Get("eth_owner")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+
+	if secret != "0xabc" {
+		t.Errorf("secret %q", secret)
+	}
+
+	if _, err := reopened.Get("sol_owner"); err == nil {
+		t.Error("absent secret is returned")
+	}
+}
+
+func TestKeystoreWrongPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "owners.json")
+
+	keystore, err := OpenKeystore(path, "passphrase", true)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	if err := keystore.Save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	if _, err := OpenKeystore(path, "another passphrase", false); err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Errorf("error %v", err)
+	}
+
+	if _, err := OpenKeystore(path, "", false); err == nil {
+		t.Error("empty passphrase is accepted")
+	}
+
+	if _, err := OpenKeystore(filepath.Join(t.TempDir(), "absent.json"), "passphrase", false); err == nil {
+		t.Error("absent keystore is opened")
+	}
+}
+
+func TestKeystoreProviderResolve(t *testing.T) {
+	dir := t.TempDir()
+
+	keystore, err := OpenKeystore(filepath.Join(dir, "owners.json"), "passphrase", true)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	for name, secret := range map[string]string{"eth_owner": "0xabc", "sol_owner": "sol-key"} {
+		if err := keystore.Set(name, secret); err != nil {
+			t.Fatalf("set: %v", err)
+		}
+	}
+
+	if err := keystore.Save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	prompts := 0
+	provider := NewKeystoreProvider(dir)
+	provider.Passphrase = func(path string) (string, error) {
+		prompts++
+		return "passphrase", nil
+	}
+
+	for reference, expected := range map[string]string{
+		"owners.json#eth_owner":                          "0xabc",
+		filepath.Join(dir, "owners.json") + "#sol_owner": "sol-key",
+	} {
+		secret, err := provider.Resolve(context.Background(), reference)
+		if err != nil {
+			t.Fatalf("%s: %v", reference, err)
+		}
+
+		if secret != expected {
+			t.Errorf("%s = %q, want %q", reference, secret, expected)
+		}
+	}
+
+	// the keystore is unlocked once
+	if prompts != 1 {
+		t.Errorf("%d passphrase prompts", prompts)
+	}
+
+	provider = NewKeystoreProvider(dir)
+	provider.Passphrase = func(path string) (string, error) { return "wrong", nil }
+	if _, err := provider.Resolve(context.Background(), "owners.json#eth_owner"); err == nil {
+		t.Error("keystore is unlocked with a wrong passphrase")
+	}
+}
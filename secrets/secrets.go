@@ -0,0 +1,61 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout limits a single secret request of the remote providers
+const DefaultTimeout = 10 * time.Second
+
+// SecretProvider returns secrets by references, e.g. "secret/asterizm#eth_owner" of "vault:secret/asterizm#eth_owner"
+type SecretProvider interface {
+	Resolve(ctx context.Context, reference string) (string, error)
+}
+
+// Resolver dispatches "scheme:reference" values to the registered providers
+// values without a registered scheme are inline secrets
+type Resolver struct {
+	providers map[string]SecretProvider
+}
+
+func NewResolver() *Resolver {
+	return &Resolver{providers: make(map[string]SecretProvider)}
+}
+
+// Register adds provider of the scheme, e.g. "vault"
+func (r *Resolver) Register(scheme string, provider SecretProvider) {
+	r.providers[scheme] = provider
+}
+
+// Resolve returns the secret of the value
+func (r *Resolver) Resolve(ctx context.Context, value string) (string, error) {
+	scheme, reference, ok := strings.Cut(value, ":")
+	if !ok {
+		return value, nil
+	}
+
+	provider, ok := r.providers[scheme]
+	if !ok {
+		return value, nil
+	}
+
+	secret, err := provider.Resolve(ctx, reference)
+	if err != nil {
+		return "", fmt.Errorf("%s secret: %w", scheme, err)
+	}
+
+	return secret, nil
+}
+
+// splitField splits "path#field" reference
+func splitField(reference string) (string, string, error) {
+	location, field, ok := strings.Cut(reference, "#")
+	if !ok || location == "" || field == "" {
+		return "", "", fmt.Errorf("reference %q must be in the path#field format", reference)
+	}
+
+	return location, field, nil
+}
@@ -0,0 +1,71 @@
+package secrets
+
+import (
+	"asterizm/builder/utils"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileProviderResolve(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "eth-owner"), []byte("0xabc\r\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	provider := NewFileProvider(dir)
+	for _, reference := range []string{"eth-owner", filepath.Join(dir, "eth-owner")} {
+		secret, err := provider.Resolve(context.Background(), reference)
+		if err != nil {
+			t.Fatalf("%s: %v", reference, err)
+		}
+
+		if secret != "0xabc" {
+			t.Errorf("%s = %q", reference, secret)
+		}
+	}
+
+	if _, err := provider.Resolve(context.Background(), "absent"); err == nil {
+		t.Error("absent file is resolved")
+	}
+}
+
+func TestResolverDispatch(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "eth-owner"), []byte("0xabc\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	encryptor := utils.NewEncryptor("key", "salt", "AES-256-CBC")
+	encrypted, err := encryptor.Encrypt([]byte("encrypted-secret"), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolver := NewResolver()
+	resolver.Register("file", NewFileProvider(dir))
+	resolver.Register("enc", NewEncryptedProvider(func() *utils.Encryptor { return encryptor }))
+
+	for value, expected := range map[string]string{
+		"file:eth-owner":           "0xabc",
+		"enc:" + string(encrypted): "encrypted-secret",
+		// values without a registered scheme are inline
+		"https://rpc.example.com": "https://rpc.example.com",
+		"plain":                   "plain",
+	} {
+		secret, err := resolver.Resolve(context.Background(), value)
+		if err != nil {
+			t.Errorf("%s: %v", value, err)
+			continue
+		}
+
+		if secret != expected {
+			t.Errorf("%s = %q, want %q", value, secret, expected)
+		}
+	}
+
+	if _, err := resolver.Resolve(context.Background(), "enc:"+string(encrypted[:len(encrypted)-4])); err == nil {
+		t.Error("damaged encrypted value is resolved")
+	}
+}
@@ -0,0 +1,137 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const maxVaultResponseSize = 1 << 20
+
+// VaultProvider reads HashiCorp Vault KV v2 secrets by "mount/path#field" references
+type VaultProvider struct {
+	// e.g. https://vault.example.com:8200
+	Address   string
+	Token     string
+	Namespace string
+	Client    *http.Client
+
+	// secrets of the read paths, several fields usually share a path
+	cache map[string]map[string]any
+}
+
+func NewVaultProvider(address, token, namespace string, client *http.Client) *VaultProvider {
+	if client == nil {
+		client = &http.Client{Timeout: DefaultTimeout}
+	}
+
+	return &VaultProvider{
+		Address:   address,
+		Token:     token,
+		Namespace: namespace,
+		Client:    client,
+		cache:     make(map[string]map[string]any),
+	}
+}
+
+// NewVaultProviderFromEnv uses VAULT_ADDR, VAULT_TOKEN and VAULT_NAMESPACE as vault cli does
+func NewVaultProviderFromEnv() *VaultProvider {
+	return NewVaultProvider(os.Getenv("VAULT_ADDR"), os.Getenv("VAULT_TOKEN"), os.Getenv("VAULT_NAMESPACE"), nil)
+}
+
+func (p *VaultProvider) Resolve(ctx context.Context, reference string) (string, error) {
+	location, field, err := splitField(reference)
+	if err != nil {
+		return "", err
+	}
+
+	data, ok := p.cache[location]
+	if !ok {
+		if data, err = p.read(ctx, location); err != nil {
+			return "", err
+		}
+		p.cache[location] = data
+	}
+
+	value, ok := data[field]
+	if !ok {
+		return "", fmt.Errorf("no field %q in %s", field, location)
+	}
+
+	secret, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("field %q of %s is not a string", field, location)
+	}
+
+	return secret, nil
+}
+
+// read returns data of the latest secret version, the first location segment is the kv mount
+func (p *VaultProvider) read(ctx context.Context, location string) (map[string]any, error) {
+	if p.Address == "" || p.Token == "" {
+		return nil, errors.New("please, set VAULT_ADDR and VAULT_TOKEN")
+	}
+
+	mount, secretPath, ok := strings.Cut(strings.Trim(location, "/"), "/")
+	if !ok || secretPath == "" {
+		return nil, fmt.Errorf("path %q must start with the kv mount, e.g. secret/asterizm", location)
+	}
+
+	endpoint := strings.TrimRight(p.Address, "/") + "/v1/" + url.PathEscape(mount) + "/data/" + escapePath(secretPath)
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("X-Vault-Token", p.Token)
+	if p.Namespace != "" {
+		request.Header.Set("X-Vault-Namespace", p.Namespace)
+	}
+
+	response, err := p.Client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", location, err)
+	}
+	defer response.Body.Close()
+
+	body := &struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+		Errors []string `json:"errors"`
+	}{}
+
+	if err := json.NewDecoder(io.LimitReader(response.Body, maxVaultResponseSize)).Decode(body); err != nil && response.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("read %s: %w", location, err)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		message := response.Status
+		if len(body.Errors) > 0 {
+			message += ": " + strings.Join(body.Errors, ", ")
+		}
+
+		return nil, fmt.Errorf("read %s: %s", location, message)
+	}
+
+	if body.Data.Data == nil {
+		return nil, fmt.Errorf("read %s: secret is deleted or empty", location)
+	}
+
+	return body.Data.Data, nil
+}
+
+func escapePath(secretPath string) string {
+	segments := strings.Split(secretPath, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,124 @@
+package secrets
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// vaultServer is a stand-in Vault which serves KV v2 responses by escaped request paths
+func vaultServer(t *testing.T, responses map[string]string, requests *int) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+
+		if r.Method != http.MethodGet {
+			t.Errorf("method %s", r.Method)
+		}
+
+		if token := r.Header.Get("X-Vault-Token"); token != "test-token" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+
+		if namespace := r.Header.Get("X-Vault-Namespace"); namespace != "team/asterizm" {
+			t.Errorf("namespace header %q", namespace)
+		}
+
+		response, ok := responses[r.URL.EscapedPath()]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":[]}`))
+			return
+		}
+
+		status, body, _ := strings.Cut(response, " ")
+		if status != "200" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestVaultProviderResolve(t *testing.T) {
+	requests := 0
+	server := vaultServer(t, map[string]string{
+		"/v1/secret/data/asterizm/owners": `200 {"data":{"data":{"eth_owner":"0xabc","sol_owner":"sol-key","nonce":1},"metadata":{"version":3}}}`,
+		"/v1/kv/data/team%20a/key%3Fx":    `200 {"data":{"data":{"value":"escaped"}}}`,
+	}, &requests)
+
+	provider := NewVaultProvider(server.URL+"/", "test-token", "team/asterizm", nil)
+
+	for reference, expected := range map[string]string{
+		"secret/asterizm/owners#eth_owner": "0xabc",
+		"secret/asterizm/owners#sol_owner": "sol-key",
+		"kv/team a/key?x#value":            "escaped",
+	} {
+		secret, err := provider.Resolve(context.Background(), reference)
+		if err != nil {
+			t.Errorf("%s: %v", reference, err)
+			continue
+		}
+
+		if secret != expected {
+			t.Errorf("%s = %q, want %q", reference, secret, expected)
+		}
+	}
+
+	// fields of a path are read once per run
+	if requests != 2 {
+		t.Errorf("%d requests, want 2", requests)
+	}
+}
+
+func TestVaultProviderErrors(t *testing.T) {
+	requests := 0
+	server := vaultServer(t, map[string]string{
+		"/v1/secret/data/asterizm": `200 {"data":{"data":{"eth_owner":"0xabc","nonce":1}}}`,
+		// deleted and destroyed versions have metadata only
+		"/v1/secret/data/deleted":   `404 {"data":{"data":null,"metadata":{"deletion_time":"2024-01-01T00:00:00Z","destroyed":false}}}`,
+		"/v1/secret/data/destroyed": `200 {"data":{"data":null,"metadata":{"deletion_time":"","destroyed":true}}}`,
+	}, &requests)
+
+	for _, test := range []struct {
+		reference string
+		token     string
+		err       string
+	}{
+		{reference: "secret/asterizm#sol_owner", err: `no field "sol_owner" in secret/asterizm`},
+		{reference: "secret/asterizm#nonce", err: `field "nonce" of secret/asterizm is not a string`},
+		{reference: "secret/deleted#eth_owner", err: "404 Not Found"},
+		{reference: "secret/destroyed#eth_owner", err: "secret is deleted or empty"},
+		{reference: "secret/absent#eth_owner", err: "read secret/absent: 404 Not Found"},
+		{reference: "secret/asterizm#eth_owner", token: "wrong-token", err: "403 Forbidden: permission denied"},
+		{reference: "secret#eth_owner", err: "must start with the kv mount"},
+		{reference: "secret/asterizm", err: "path#field format"},
+	} {
+		token := test.token
+		if token == "" {
+			token = "test-token"
+		}
+
+		_, err := NewVaultProvider(server.URL, token, "team/asterizm", nil).Resolve(context.Background(), test.reference)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: error %v, want %q", test.reference, err, test.err)
+		}
+	}
+}
+
+func TestVaultProviderFromEnv(t *testing.T) {
+	t.Setenv("VAULT_ADDR", "")
+	t.Setenv("VAULT_TOKEN", "")
+
+	_, err := NewVaultProviderFromEnv().Resolve(context.Background(), "secret/asterizm#eth_owner")
+	if err == nil || !strings.Contains(err.Error(), "VAULT_ADDR and VAULT_TOKEN") {
+		t.Errorf("error %v", err)
+	}
+}
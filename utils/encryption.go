@@ -9,17 +9,26 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 type Encryptor struct {
 	key          string
 	salt         string
 	cipherMethod string
+	kdf          Kdf
+
+	// derived master keys, password-based derivations are slow
+	masterKeys sync.Map
 }
 
 type CipherMode struct {
 	encryptFn func(cipher.Block, []byte) any
 	decryptFn func(cipher.Block, []byte) any
+	// authenticated modes are used without padding and hmac
+	aeadFn func(cipher.Block) (cipher.AEAD, error)
+	// key lengths supported by the client-server, all cipherLengths if empty
+	lengths []string
 }
 
 const (
@@ -62,6 +71,10 @@ var (
 				return cipher.NewCTR(b, iv)
 			},
 		},
+		"GCM": {
+			aeadFn:  cipher.NewGCM,
+			lengths: []string{"128", "256"},
+		},
 	}
 	cipherLengths = []string{"128", "192", "256"}
 )
@@ -74,12 +87,30 @@ func NewEncryptor(key, salt, cipherMethod string) *Encryptor {
 	}
 }
 
+// WithKdf sets master key derivation of the new values
+// decryption uses the derivation recorded in the value
+func (e *Encryptor) WithKdf(kdf Kdf) *Encryptor {
+	e.kdf = kdf.WithDefaults()
+	return e
+}
+
+// Encrypt returns v2 envelope of the plaintext, it is read by the builder only
 func (e *Encryptor) Encrypt(plaintext []byte, key string, salt string) ([]byte, error) {
 	if plaintext == nil || len(plaintext) == 0 {
 		return nil, fmt.Errorf("empty string")
 	}
 
-	pk, err := e.buildKey(key, salt)
+	return e.encryptV2(plaintext, key, salt, "")
+}
+
+// EncryptLegacy returns legacy base64(iv | hmac | ciphertext) or base64(nonce | ciphertext | tag) of the authenticated mode
+// the client-server container decrypts this format only, v2 envelopes are read by the builder
+func (e *Encryptor) EncryptLegacy(plaintext []byte, key string, salt string) ([]byte, error) {
+	if len(plaintext) == 0 {
+		return nil, fmt.Errorf("empty string")
+	}
+
+	pk, err := e.buildKey(key, salt, Kdf{Algorithm: KdfLegacy})
 	if err != nil {
 		return nil, err
 	}
@@ -89,6 +120,10 @@ func (e *Encryptor) Encrypt(plaintext []byte, key string, salt string) ([]byte,
 		return nil, err
 	}
 
+	if cipherMode.aeadFn != nil {
+		return cipherMode.Seal(pk[:keySize], plaintext)
+	}
+
 	iv, err := GenerateRandomBytes(aes.BlockSize)
 	if err != nil {
 		return nil, err
@@ -108,12 +143,47 @@ func (e *Encryptor) Encrypt(plaintext []byte, key string, salt string) ([]byte,
 	return buf, nil
 }
 
+// EncryptFor returns v2 envelope of the plaintext bound to the network
+// the value can be decrypted only with DecryptFor of the same network
+func (e *Encryptor) EncryptFor(network string, plaintext []byte) ([]byte, error) {
+	if len(plaintext) == 0 {
+		return nil, fmt.Errorf("empty string")
+	}
+
+	return e.encryptV2(plaintext, "", "", network)
+}
+
+// DecryptFor decrypts the value bound to the network
+// values which are not bound to any network (legacy or Encrypt) are accepted as well, so they aren't protected
+// from being copied to another network, the deploy re-encrypts them with the network subkeys (see IsBoundTo)
+func (e *Encryptor) DecryptFor(network string, encryptedText []byte) ([]byte, error) {
+	if network == "" || !IsEnvelopeV2(encryptedText) {
+		return e.Decrypt(encryptedText, "", "")
+	}
+
+	plaintext, err := e.decryptV2(encryptedText, "", "", network)
+	if err == nil {
+		return plaintext, nil
+	}
+
+	if plaintext, unboundErr := e.decryptV2(encryptedText, "", "", ""); unboundErr == nil {
+		return plaintext, nil
+	}
+
+	return nil, err
+}
+
+// Decrypt accepts v2 envelope or legacy base64(iv | hmac | ciphertext) format
 func (e *Encryptor) Decrypt(encryptedText []byte, key string, salt string) ([]byte, error) {
 	if encryptedText == nil || len(encryptedText) == 0 {
 		return nil, fmt.Errorf("empty string")
 	}
 
-	pk, err := e.buildKey(key, salt)
+	if IsEnvelopeV2(encryptedText) {
+		return e.decryptV2(encryptedText, key, salt, "")
+	}
+
+	pk, err := e.buildKey(key, salt, Kdf{Algorithm: KdfLegacy})
 	if err != nil {
 		return nil, err
 	}
@@ -124,7 +194,16 @@ func (e *Encryptor) Decrypt(encryptedText []byte, key string, salt string) ([]by
 		return nil, err
 	}
 
+	cipherMode, keySize, err := e.getCipher()
+	if err != nil {
+		return nil, err
+	}
+
 	c := dbuf[:n]
+	if cipherMode.aeadFn != nil {
+		return cipherMode.Open(pk[:keySize], c)
+	}
+
 	if len(c) < (ivLen + sha2len) {
 		return nil, fmt.Errorf("bad decrypted lenght")
 	}
@@ -132,16 +211,7 @@ func (e *Encryptor) Decrypt(encryptedText []byte, key string, salt string) ([]by
 	ciphertext := c[ivLen+sha2len:]
 	iv := c[:ivLen]
 
-	cipherMode, keySize, err := e.getCipher()
-	if err != nil {
-		return nil, err
-	}
-
-	plaintext, err := cipherMode.Decrypt(pk[:keySize], iv, ciphertext)
-	if err != nil {
-		return nil, err
-	}
-
+	// verify before decryption, so arbitrary values are rejected without decrypting
 	calculatedMac := e.computeHmac(pk, ciphertext)
 	receivedMac := c[ivLen : ivLen+sha2len]
 
@@ -149,6 +219,11 @@ func (e *Encryptor) Decrypt(encryptedText []byte, key string, salt string) ([]by
 		return nil, fmt.Errorf("HMAC verification failed")
 	}
 
+	plaintext, err := cipherMode.Decrypt(pk[:keySize], iv, ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
 	result, err := Pkcs7UnPadding(plaintext)
 
 	if err != nil {
@@ -158,6 +233,34 @@ func (e *Encryptor) Decrypt(encryptedText []byte, key string, salt string) ([]by
 	return result, nil
 }
 
+// IsEncrypted checks that the value is encrypted with the encryptor key, salt and cipher method
+func (e *Encryptor) IsEncrypted(value []byte) bool {
+	_, err := e.Decrypt(value, "", "")
+	return err == nil
+}
+
+// IsBoundTo checks that the value is encrypted with the subkeys of the network, unbound values are rejected
+func (e *Encryptor) IsBoundTo(network string, value []byte) bool {
+	if network == "" || !IsEnvelopeV2(value) {
+		return false
+	}
+
+	_, err := e.decryptV2(value, "", "", network)
+	return err == nil
+}
+
+// IsEncryptedFor checks that the value is encrypted with the encryptor settings for the network or for any network
+func (e *Encryptor) IsEncryptedFor(network string, value []byte) bool {
+	_, err := e.DecryptFor(network, value)
+	return err == nil
+}
+
+// ValidateCipherMethod checks Utils.Encryption.CipherMethod format
+func ValidateCipherMethod(cipherMethod string) error {
+	_, _, err := NewEncryptor("", "", cipherMethod).getCipher()
+	return err
+}
+
 // parse and validate cipher from config
 // returns cipher mode and hash key size
 func (e *Encryptor) getCipher() (*CipherMode, int, error) {
@@ -169,7 +272,7 @@ func (e *Encryptor) getCipher() (*CipherMode, int, error) {
 		"cypher method format is: {%v}-{%v}-{%v}",
 		cipherName,
 		strings.Join(cipherLengths, ","),
-		strings.Join(MapKeys(availableModes), ","),
+		strings.Join(SortedKeys(availableModes), ","),
 	)
 
 	params := strings.Split(strings.ToUpper(e.cipherMethod), "-") // AES-{size}-{mode}
@@ -187,6 +290,10 @@ func (e *Encryptor) getCipher() (*CipherMode, int, error) {
 		return nil, 0, formattedError
 	}
 
+	if len(mode.lengths) > 0 && !InSlice(params[1], mode.lengths) {
+		return nil, 0, fmt.Errorf("%v-%v supports %v key lengths only", cipherName, params[2], strings.Join(mode.lengths, ","))
+	}
+
 	for _, value := range cipherLengths {
 		if value == params[1] {
 			if parsed, err := strconv.Atoi(value); err == nil {
@@ -198,7 +305,7 @@ func (e *Encryptor) getCipher() (*CipherMode, int, error) {
 	return nil, 0, formattedError
 }
 
-func (e *Encryptor) buildKey(key string, salt string) ([]byte, error) {
+func (e *Encryptor) buildKey(key string, salt string, kdf Kdf) ([]byte, error) {
 	if key == "" {
 		if e.key == "" {
 			return nil, fmt.Errorf("undefined encryption key")
@@ -215,12 +322,15 @@ func (e *Encryptor) buildKey(key string, salt string) ([]byte, error) {
 		salt = e.salt
 	}
 
-	hash := sha256.New()
-	hash.Write([]byte(salt))
-	hash.Write([]byte(key))
-	hash.Write([]byte(salt))
+	cacheKey := strings.Join([]string{string(kdf.header()), key, salt}, "\x00")
+	if masterKey, ok := e.masterKeys.Load(cacheKey); ok {
+		return masterKey.([]byte), nil
+	}
+
+	masterKey := kdf.deriveKey(key, salt)
+	e.masterKeys.Store(cacheKey, masterKey)
 
-	return hash.Sum(nil), nil
+	return masterKey, nil
 }
 
 func (e *Encryptor) computeHmac(key []byte, data ...[]byte) []byte {
@@ -261,6 +371,9 @@ func (m *CipherMode) Decrypt(key []byte, iv []byte, ciphertext []byte) ([]byte,
 
 	switch mode := mode.(type) {
 	case cipher.BlockMode:
+		if len(ciphertext)%mode.BlockSize() != 0 {
+			return nil, fmt.Errorf("ciphertext is not a multiple of the block size")
+		}
 		mode.CryptBlocks(plaintext, ciphertext)
 	case cipher.Stream:
 		mode.XORKeyStream(plaintext, ciphertext)
@@ -268,3 +381,51 @@ func (m *CipherMode) Decrypt(key []byte, iv []byte, ciphertext []byte) ([]byte,
 
 	return plaintext, err
 }
+
+// Seal encrypts plaintext with the authenticated mode
+// returns legacy base64(nonce | ciphertext | tag)
+func (m *CipherMode) Seal(key []byte, plaintext []byte) ([]byte, error) {
+	aead, err := m.aead(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := GenerateRandomBytes(aead.NonceSize())
+	if err != nil {
+		return nil, err
+	}
+
+	sealed := aead.Seal(nonce, nonce, plaintext, nil)
+	buf := make([]byte, base64.StdEncoding.EncodedLen(len(sealed)))
+	base64.StdEncoding.Encode(buf, sealed)
+
+	return buf, nil
+}
+
+// Open decrypts and authenticates legacy nonce | ciphertext | tag of the authenticated mode
+func (m *CipherMode) Open(key []byte, sealed []byte) ([]byte, error) {
+	aead, err := m.aead(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < aead.NonceSize()+aead.Overhead() {
+		return nil, fmt.Errorf("bad decrypted lenght")
+	}
+
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("authentication failed")
+	}
+
+	return plaintext, nil
+}
+
+func (m *CipherMode) aead(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.aeadFn(block)
+}
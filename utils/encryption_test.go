@@ -0,0 +1,241 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+)
+
+const (
+	testKey       = "test-encryption-key"
+	testSalt      = "test-encryption-salt"
+	testPlaintext = "0x4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+)
+
+var testModes = []string{"AES-256-CBC", "AES-128-CTR", "AES-128-GCM", "AES-256-GCM"}
+
+// legacy values of the client-server format generated by testdata/legacy_vectors.js with node crypto,
+// not by the console itself, run it again if the format changes
+// key is sha256(salt | key | salt) truncated to the key length
+// GCM is nonce 000102030405060708090a0b | ciphertext | tag, CBC is iv 0f0e..00 | hmac | ciphertext
+var legacyVectors = map[string]string{
+	"AES-128-GCM": "AAECAwQFBgcICQoLLLxVpqQuk4Ejx8iVvQAqyqnAVpuUtyfTtMimwQ/6GXWbDOyA+zJaBkpvSRtbydzTQQ0teOzOvO/bt6WOzdumIpba+RyPTIuLFAbERCTtID0SXQ==",
+	"AES-256-GCM": "AAECAwQFBgcICQoLBlqoRg5tXV0Cu/ev13uQcRg27zcS7rSQsIXPxk2hXNmghOWgr9JO89wA2AtCk4Dalta6CWqCycwve0HhF6cS3oJXjTpFLQjlKtPtTbtG/4lG/Q==",
+	"AES-256-CBC": "Dw4NDAsKCQgHBgUEAwIBAJN0gdfxL3FjdowR+7CIOxpDkGzqGNl02UyZs/bboP8D1Xq5eL1IVqvI5YqZYNOt6fL/09hpKARl8o3qwh2xewifJerWmJzz/vjbKX+99O7FS78vzU8zLmVLAKRG0fdNuvLohi+O44OxmM3DydmtJ5U=",
+}
+
+func TestDecryptLegacyVectors(t *testing.T) {
+	for method, value := range legacyVectors {
+		t.Run(method, func(t *testing.T) {
+			plaintext, err := NewEncryptor(testKey, testSalt, method).Decrypt([]byte(value), "", "")
+			if err != nil {
+				t.Fatalf("decrypt: %v", err)
+			}
+
+			if string(plaintext) != testPlaintext {
+				t.Errorf("decrypted %q", plaintext)
+			}
+		})
+	}
+}
+
+// values of EncryptLegacy are opened by a plain GCM implementation with the client-server layout
+func TestEncryptLegacyGcmLayout(t *testing.T) {
+	for method, keySize := range map[string]int{"AES-128-GCM": 16, "AES-256-GCM": 32} {
+		t.Run(method, func(t *testing.T) {
+			encrypted, err := NewEncryptor(testKey, testSalt, method).EncryptLegacy([]byte(testPlaintext), "", "")
+			if err != nil {
+				t.Fatalf("encrypt: %v", err)
+			}
+
+			sealed, err := base64.StdEncoding.DecodeString(string(encrypted))
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+
+			key := sha256.Sum256([]byte(testSalt + testKey + testSalt))
+			block, err := aes.NewCipher(key[:keySize])
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			aead, err := cipher.NewGCM(block)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(sealed) != aead.NonceSize()+len(testPlaintext)+aead.Overhead() {
+				t.Fatalf("sealed length %d", len(sealed))
+			}
+
+			plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+			if err != nil {
+				t.Fatalf("open: %v", err)
+			}
+
+			if string(plaintext) != testPlaintext {
+				t.Errorf("opened %q", plaintext)
+			}
+		})
+	}
+}
+
+func TestEncryptDecryptRoundTrip(t *testing.T) {
+	for _, method := range testModes {
+		encryptor := NewEncryptor(testKey, testSalt, method)
+
+		for name, encrypt := range map[string]func([]byte, string, string) ([]byte, error){
+			"v2":     encryptor.Encrypt,
+			"legacy": encryptor.EncryptLegacy,
+		} {
+			t.Run(method+" "+name, func(t *testing.T) {
+				encrypted, err := encrypt([]byte(testPlaintext), "", "")
+				if err != nil {
+					t.Fatalf("encrypt: %v", err)
+				}
+
+				if IsEnvelopeV2(encrypted) != (name == "v2") {
+					t.Errorf("%s value format %q", name, encrypted)
+				}
+
+				plaintext, err := encryptor.Decrypt(encrypted, "", "")
+				if err != nil {
+					t.Fatalf("decrypt: %v", err)
+				}
+
+				if string(plaintext) != testPlaintext {
+					t.Errorf("decrypted %q", plaintext)
+				}
+			})
+		}
+	}
+}
+
+func TestDecryptRejectsTamperedValues(t *testing.T) {
+	for _, method := range testModes {
+		encryptor := NewEncryptor(testKey, testSalt, method)
+
+		for name, encrypt := range map[string]func([]byte, string, string) ([]byte, error){
+			"v2":     encryptor.Encrypt,
+			"legacy": encryptor.EncryptLegacy,
+		} {
+			t.Run(method+" "+name, func(t *testing.T) {
+				encrypted, err := encrypt([]byte(testPlaintext), "", "")
+				if err != nil {
+					t.Fatalf("encrypt: %v", err)
+				}
+
+				prefix := ""
+				if IsEnvelopeV2(encrypted) {
+					prefix = EnvelopeV2Prefix
+				}
+
+				raw, err := base64.StdEncoding.DecodeString(string(encrypted[len(prefix):]))
+				if err != nil {
+					t.Fatalf("decode: %v", err)
+				}
+
+				// header, iv or nonce, ciphertext and mac or tag
+				// hmac of the legacy format covers the ciphertext only, the client-server format doesn't authenticate iv
+				start := 0
+				if name == "legacy" && method != "AES-128-GCM" && method != "AES-256-GCM" {
+					start = ivLen
+				}
+
+				for _, position := range []int{start, len(raw) / 2, len(raw) - 1} {
+					tampered := append([]byte{}, raw...)
+					tampered[position] ^= 0x01
+
+					value := []byte(prefix + base64.StdEncoding.EncodeToString(tampered))
+					if _, err := encryptor.Decrypt(value, "", ""); err == nil {
+						t.Errorf("value tampered at %d is decrypted", position)
+					}
+				}
+
+				if _, err := NewEncryptor(testKey, "another-salt", method).Decrypt(encrypted, "", ""); err == nil {
+					t.Error("value is decrypted with another salt")
+				}
+			})
+		}
+	}
+}
+
+func TestValidateCipherMethod(t *testing.T) {
+	for _, method := range []string{"AES-128-CBC", "aes-192-ctr", "AES-256-OFB", "AES-256-CFB", "AES-128-GCM", "AES-256-GCM"} {
+		if err := ValidateCipherMethod(method); err != nil {
+			t.Errorf("%s is rejected: %v", method, err)
+		}
+	}
+
+	// the client-server doesn't support AES-192-GCM
+	for _, method := range []string{"", "AES-192-GCM", "AES-512-CBC", "DES-128-CBC", "AES-256-XTS", "AES-256"} {
+		if err := ValidateCipherMethod(method); err == nil {
+			t.Errorf("%s is accepted", method)
+		}
+	}
+}
+
+func TestDecryptForNetworkBinding(t *testing.T) {
+	encryptor := NewEncryptor(testKey, testSalt, "AES-256-CBC")
+
+	bound, err := encryptor.EncryptFor("ETH", []byte(testPlaintext))
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+
+	plaintext, err := encryptor.DecryptFor("ETH", bound)
+	if err != nil {
+		t.Fatalf("decrypt for ETH: %v", err)
+	}
+
+	if string(plaintext) != testPlaintext {
+		t.Errorf("decrypted %q", plaintext)
+	}
+
+	// a value copied to another network entry
+	if _, err := encryptor.DecryptFor("SOL", bound); err == nil {
+		t.Error("ETH value is decrypted for SOL")
+	}
+
+	if _, err := encryptor.Decrypt(bound, "", ""); err == nil {
+		t.Error("ETH value is decrypted without the network")
+	}
+
+	if !encryptor.IsBoundTo("ETH", bound) || encryptor.IsBoundTo("SOL", bound) {
+		t.Error("ETH value binding isn't detected")
+	}
+}
+
+// unbound values are accepted for any network, the deploy re-encrypts them with IsBoundTo check
+func TestDecryptForUnboundValues(t *testing.T) {
+	encryptor := NewEncryptor(testKey, testSalt, "AES-256-CBC")
+
+	for name, encrypt := range map[string]func([]byte, string, string) ([]byte, error){
+		"v2":     encryptor.Encrypt,
+		"legacy": encryptor.EncryptLegacy,
+	} {
+		t.Run(name, func(t *testing.T) {
+			unbound, err := encrypt([]byte(testPlaintext), "", "")
+			if err != nil {
+				t.Fatalf("encrypt: %v", err)
+			}
+
+			for _, network := range []string{"ETH", "SOL"} {
+				plaintext, err := encryptor.DecryptFor(network, unbound)
+				if err != nil {
+					t.Fatalf("decrypt for %s: %v", network, err)
+				}
+
+				if string(plaintext) != testPlaintext {
+					t.Errorf("decrypted %q", plaintext)
+				}
+
+				if encryptor.IsBoundTo(network, unbound) {
+					t.Errorf("unbound value is bound to %s", network)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// envelope v2: "v2:" + base64(header | iv | ciphertext | mac)
+// header: cipher method length | cipher method | kdf id | kdf cost parameters
+// mac is HMAC-SHA256 over "v2:" | header | iv | ciphertext, authenticated modes use the header as additional data instead
+// values bound to a network use subkeys derived with the network code, the envelope doesn't record it
+const EnvelopeV2Prefix = "v2:"
+
+// sha256(salt | key | salt) of the legacy format
+const kdfLegacy byte = 0
+
+var (
+	encryptionKeyInfo = []byte("asterizm encryption key v2")
+	macKeyInfo        = []byte("asterizm mac key v2")
+)
+
+// IsEnvelopeV2 checks that the value has the v2 envelope prefix
+func IsEnvelopeV2(value []byte) bool {
+	return bytes.HasPrefix(value, []byte(EnvelopeV2Prefix))
+}
+
+func (e *Encryptor) encryptV2(plaintext []byte, key string, salt string, network string) ([]byte, error) {
+	if err := e.kdf.Validate(); err != nil {
+		return nil, err
+	}
+
+	masterKey, err := e.buildKey(key, salt, e.kdf)
+	if err != nil {
+		return nil, err
+	}
+
+	cipherMode, keySize, err := e.getCipher()
+	if err != nil {
+		return nil, err
+	}
+
+	cipherMethod := strings.ToUpper(e.cipherMethod)
+	header := append([]byte{byte(len(cipherMethod))}, cipherMethod...)
+	header = append(header, e.kdf.header()...)
+
+	encryptionKey, macKey, err := deriveSubkeys(masterKey, keySize, network)
+	if err != nil {
+		return nil, err
+	}
+
+	var body []byte
+	if cipherMode.aeadFn != nil {
+		aead, err := cipherMode.aead(encryptionKey)
+		if err != nil {
+			return nil, err
+		}
+
+		nonce, err := GenerateRandomBytes(aead.NonceSize())
+		if err != nil {
+			return nil, err
+		}
+
+		body = aead.Seal(append(header, nonce...), nonce, plaintext, header)
+	} else {
+		iv, err := GenerateRandomBytes(aes.BlockSize)
+		if err != nil {
+			return nil, err
+		}
+
+		ciphertext, err := cipherMode.Encrypt(encryptionKey, iv, Pkcs7Padding(plaintext, aes.BlockSize))
+		if err != nil {
+			return nil, err
+		}
+
+		body = append(append(header, iv...), ciphertext...)
+		body = append(body, e.computeHmac(macKey, []byte(EnvelopeV2Prefix), body)...)
+	}
+
+	return []byte(EnvelopeV2Prefix + base64.StdEncoding.EncodeToString(body)), nil
+}
+
+// decryptV2 uses the cipher method of the envelope header, so values stay readable after CipherMethod change
+func (e *Encryptor) decryptV2(encryptedText []byte, key string, salt string, network string) ([]byte, error) {
+	body, err := base64.StdEncoding.DecodeString(string(encryptedText[len(EnvelopeV2Prefix):]))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(body) < 1 || len(body) < 1+int(body[0]) {
+		return nil, errors.New("bad envelope header")
+	}
+
+	cipherMethod := string(body[1 : 1+body[0]])
+	kdf, kdfLen, err := parseKdfHeader(body[1+body[0]:])
+	if err != nil {
+		return nil, err
+	}
+
+	headerLen := 1 + int(body[0]) + kdfLen
+	header, payload := body[:headerLen], body[headerLen:]
+
+	methodEncryptor := NewEncryptor(e.key, e.salt, cipherMethod)
+	cipherMode, keySize, err := methodEncryptor.getCipher()
+	if err != nil {
+		return nil, err
+	}
+
+	masterKey, err := e.buildKey(key, salt, kdf)
+	if err != nil {
+		return nil, err
+	}
+
+	encryptionKey, macKey, err := deriveSubkeys(masterKey, keySize, network)
+	if err != nil {
+		return nil, err
+	}
+
+	if cipherMode.aeadFn != nil {
+		aead, err := cipherMode.aead(encryptionKey)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(payload) < aead.NonceSize()+aead.Overhead() {
+			return nil, errors.New("bad decrypted lenght")
+		}
+
+		plaintext, err := aead.Open(nil, payload[:aead.NonceSize()], payload[aead.NonceSize():], header)
+		if err != nil {
+			return nil, errors.New("authentication failed")
+		}
+
+		return plaintext, nil
+	}
+
+	if len(payload) < ivLen+sha2len {
+		return nil, errors.New("bad decrypted lenght")
+	}
+
+	macStart := len(body) - sha2len
+	if !hmac.Equal(e.computeHmac(macKey, []byte(EnvelopeV2Prefix), body[:macStart]), body[macStart:]) {
+		return nil, errors.New("HMAC verification failed")
+	}
+
+	plaintext, err := cipherMode.Decrypt(encryptionKey, payload[:ivLen], payload[ivLen:len(payload)-sha2len])
+	if err != nil {
+		return nil, err
+	}
+
+	return Pkcs7UnPadding(plaintext)
+}
+
+// deriveSubkeys derives independent encryption and mac keys from the master key
+// a non-empty network is appended to the info, so the keys of different networks are independent
+func deriveSubkeys(masterKey []byte, keySize int, network string) ([]byte, []byte, error) {
+	encryptionInfo, macInfo := encryptionKeyInfo, macKeyInfo
+	if network != "" {
+		encryptionInfo = append(append(append([]byte{}, encryptionKeyInfo...), ' '), network...)
+		macInfo = append(append(append([]byte{}, macKeyInfo...), ' '), network...)
+	}
+
+	encryptionKey := make([]byte, keySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, masterKey, nil, encryptionInfo), encryptionKey); err != nil {
+		return nil, nil, err
+	}
+
+	macKey := make([]byte, sha2len)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, masterKey, nil, macInfo), macKey); err != nil {
+		return nil, nil, err
+	}
+
+	return encryptionKey, macKey, nil
+}
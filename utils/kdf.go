@@ -0,0 +1,245 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	KdfLegacy   = "legacy"
+	KdfPbkdf2   = "pbkdf2"
+	KdfScrypt   = "scrypt"
+	KdfArgon2id = "argon2id"
+
+	masterKeyLen = 32
+
+	// cost limits, parameters are read from envelope, signature and stream headers
+	// a crafted value must not make the derivation take unbounded memory or time
+	maxKdfMemory          = 1 << 30 // bytes
+	maxPbkdf2Iterations   = 10000000
+	maxArgon2idIterations = 64
+	maxArgon2idThreads    = 64
+	maxScryptN            = 1 << 20
+	maxScryptR            = 64
+	maxScryptP            = 16
+)
+
+// envelope kdf ids
+const (
+	kdfPbkdf2 byte = iota + 1
+	kdfScrypt
+	kdfArgon2id
+)
+
+// Kdf is a master key derivation from the encryption key and salt
+// empty algorithm means the legacy sha256(salt | key | salt)
+type Kdf struct {
+	Algorithm string
+	// pbkdf2 iterations or argon2id passes
+	Iterations uint32
+	// argon2id memory in KiB
+	Memory uint32
+	// argon2id parallelism
+	Threads uint8
+	// scrypt cost parameters
+	N uint32
+	R uint32
+	P uint32
+}
+
+// WithDefaults fills empty cost parameters with the recommended values
+func (k Kdf) WithDefaults() Kdf {
+	k.Algorithm = strings.ToLower(k.Algorithm)
+
+	switch k.Algorithm {
+	case KdfPbkdf2:
+		if k.Iterations == 0 {
+			k.Iterations = 600000
+		}
+	case KdfScrypt:
+		if k.N == 0 {
+			k.N = 1 << 15
+		}
+		if k.R == 0 {
+			k.R = 8
+		}
+		if k.P == 0 {
+			k.P = 1
+		}
+	case KdfArgon2id:
+		if k.Iterations == 0 {
+			k.Iterations = 3
+		}
+		if k.Memory == 0 {
+			k.Memory = 64 * 1024
+		}
+		if k.Threads == 0 {
+			k.Threads = 4
+		}
+	}
+
+	return k
+}
+
+// Validate checks algorithm and cost parameters
+func (k Kdf) Validate() error {
+	k = k.WithDefaults()
+
+	switch k.Algorithm {
+	case "", KdfLegacy:
+		return nil
+	case KdfPbkdf2:
+		if k.Iterations > maxPbkdf2Iterations {
+			return fmt.Errorf("pbkdf2 iterations must be at most %d", maxPbkdf2Iterations)
+		}
+
+		return nil
+	case KdfScrypt:
+		if k.N < 2 || k.N&(k.N-1) != 0 || k.N > maxScryptN {
+			return fmt.Errorf("scrypt N must be a power of two from 2 to %d", maxScryptN)
+		}
+
+		if k.R > maxScryptR || k.P > maxScryptP {
+			return fmt.Errorf("scrypt R must be at most %d and P at most %d", maxScryptR, maxScryptP)
+		}
+
+		// scrypt takes 128 * N * R bytes
+		if 128*uint64(k.N)*uint64(k.R) > maxKdfMemory {
+			return fmt.Errorf("scrypt N * R must take at most %d MiB", maxKdfMemory>>20)
+		}
+
+		return nil
+	case KdfArgon2id:
+		if k.Iterations > maxArgon2idIterations {
+			return fmt.Errorf("argon2id iterations must be at most %d", maxArgon2idIterations)
+		}
+
+		if k.Threads > maxArgon2idThreads {
+			return fmt.Errorf("argon2id threads must be at most %d", maxArgon2idThreads)
+		}
+
+		if k.Memory < 8*uint32(k.Threads) {
+			return errors.New("argon2id memory must be at least 8 KiB per thread")
+		}
+
+		if uint64(k.Memory)*1024 > maxKdfMemory {
+			return fmt.Errorf("argon2id memory must be at most %d KiB", maxKdfMemory>>10)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("kdf algorithm must be one of %s", strings.Join([]string{KdfLegacy, KdfPbkdf2, KdfScrypt, KdfArgon2id}, ", "))
+}
+
+// deriveKey returns master key of the encryption key and salt
+func (k Kdf) deriveKey(key, salt string) []byte {
+	switch k.Algorithm {
+	case KdfPbkdf2:
+		return pbkdf2.Key([]byte(key), []byte(salt), int(k.Iterations), masterKeyLen, sha256.New)
+	case KdfScrypt:
+		// parameters are validated before
+		derived, _ := scrypt.Key([]byte(key), []byte(salt), int(k.N), int(k.R), int(k.P), masterKeyLen)
+		return derived
+	case KdfArgon2id:
+		return argon2.IDKey([]byte(key), []byte(salt), k.Iterations, k.Memory, k.Threads, masterKeyLen)
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(salt))
+	hash.Write([]byte(key))
+	hash.Write([]byte(salt))
+
+	return hash.Sum(nil)
+}
+
+// header returns envelope kdf id with cost parameters
+func (k Kdf) header() []byte {
+	switch k.Algorithm {
+	case KdfPbkdf2:
+		return binary.BigEndian.AppendUint32([]byte{kdfPbkdf2}, k.Iterations)
+	case KdfScrypt:
+		header := binary.BigEndian.AppendUint32([]byte{kdfScrypt}, k.N)
+		header = binary.BigEndian.AppendUint32(header, k.R)
+		return binary.BigEndian.AppendUint32(header, k.P)
+	case KdfArgon2id:
+		header := binary.BigEndian.AppendUint32([]byte{kdfArgon2id}, k.Iterations)
+		header = binary.BigEndian.AppendUint32(header, k.Memory)
+		return append(header, k.Threads)
+	}
+
+	return []byte{kdfLegacy}
+}
+
+// kdfHeaderLen returns envelope header length of the kdf id, 0 if the id is unknown
+func kdfHeaderLen(id byte) int {
+	switch id {
+	case kdfLegacy:
+		return 1
+	case kdfPbkdf2:
+		return 1 + 4
+	case kdfScrypt:
+		return 1 + 3*4
+	case kdfArgon2id:
+		return 1 + 2*4 + 1
+	}
+
+	return 0
+}
+
+// parseKdfHeader returns kdf of the envelope header and the header length
+func parseKdfHeader(data []byte) (Kdf, int, error) {
+	if len(data) == 0 {
+		return Kdf{}, 0, errors.New("bad envelope header")
+	}
+
+	length := kdfHeaderLen(data[0])
+	if length == 0 {
+		return Kdf{}, 0, fmt.Errorf("unknown envelope kdf %d", data[0])
+	}
+
+	if len(data) < length {
+		return Kdf{}, 0, errors.New("bad envelope header")
+	}
+
+	var kdf Kdf
+	switch data[0] {
+	case kdfLegacy:
+		return Kdf{Algorithm: KdfLegacy}, length, nil
+	case kdfPbkdf2:
+		kdf = Kdf{Algorithm: KdfPbkdf2, Iterations: binary.BigEndian.Uint32(data[1:])}
+	case kdfScrypt:
+		kdf = Kdf{
+			Algorithm: KdfScrypt,
+			N:         binary.BigEndian.Uint32(data[1:]),
+			R:         binary.BigEndian.Uint32(data[5:]),
+			P:         binary.BigEndian.Uint32(data[9:]),
+		}
+	case kdfArgon2id:
+		kdf = Kdf{
+			Algorithm:  KdfArgon2id,
+			Iterations: binary.BigEndian.Uint32(data[1:]),
+			Memory:     binary.BigEndian.Uint32(data[5:]),
+			Threads:    data[9],
+		}
+	}
+
+	// zero parameters would be replaced with defaults, which are not what the value was encrypted with
+	if kdf.WithDefaults() != kdf {
+		return Kdf{}, 0, errors.New("bad envelope kdf parameters")
+	}
+
+	// cost limits are checked before any derivation with the parameters
+	if err := kdf.Validate(); err != nil {
+		return Kdf{}, 0, err
+	}
+
+	return kdf, length, nil
+}
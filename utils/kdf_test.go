@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"testing"
+)
+
+// cheap parameters, the tests check encoding, not the cost
+var testKdfs = []Kdf{
+	{Algorithm: KdfLegacy},
+	{Algorithm: KdfPbkdf2, Iterations: 1000},
+	{Algorithm: KdfScrypt, N: 1024, R: 8, P: 1},
+	{Algorithm: KdfArgon2id, Iterations: 1, Memory: 64, Threads: 1},
+}
+
+func TestKdfHeaderRoundTrip(t *testing.T) {
+	for _, kdf := range testKdfs {
+		t.Run(kdf.Algorithm, func(t *testing.T) {
+			header := kdf.header()
+
+			parsed, length, err := parseKdfHeader(append(header, 0xff))
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+
+			if parsed != kdf || length != len(header) {
+				t.Errorf("parsed %+v of %d bytes, want %+v of %d bytes", parsed, length, kdf, len(header))
+			}
+		})
+	}
+}
+
+func TestKdfEncryptDecrypt(t *testing.T) {
+	for _, kdf := range testKdfs {
+		t.Run(kdf.Algorithm, func(t *testing.T) {
+			encryptor := NewEncryptor("key", "salt", "AES-256-CBC").WithKdf(kdf)
+
+			encrypted, err := encryptor.Encrypt([]byte("secret"), "", "")
+			if err != nil {
+				t.Fatalf("encrypt: %v", err)
+			}
+
+			// decryption takes the kdf from the value
+			plaintext, err := NewEncryptor("key", "salt", "AES-256-CBC").Decrypt(encrypted, "", "")
+			if err != nil {
+				t.Fatalf("decrypt: %v", err)
+			}
+
+			if string(plaintext) != "secret" {
+				t.Errorf("decrypted %q", plaintext)
+			}
+		})
+	}
+}
+
+func TestKdfHeaderRejectsExpensiveParameters(t *testing.T) {
+	for name, header := range map[string][]byte{
+		"pbkdf2 iterations":   kdfHeader(kdfPbkdf2, maxPbkdf2Iterations+1),
+		"scrypt N 2^31":       kdfHeader(kdfScrypt, 1<<31, 8, 1),
+		"scrypt N":            kdfHeader(kdfScrypt, maxScryptN*2, 1, 1),
+		"scrypt R":            kdfHeader(kdfScrypt, 1024, maxScryptR+1, 1),
+		"scrypt P":            kdfHeader(kdfScrypt, 1024, 8, maxScryptP+1),
+		"scrypt memory":       kdfHeader(kdfScrypt, maxScryptN, 16, 1),
+		"argon2id memory":     append(kdfHeader(kdfArgon2id, 1, 1<<32-1), 4),
+		"argon2id iterations": append(kdfHeader(kdfArgon2id, 1<<32-1, 64), 1),
+		"argon2id threads":    append(kdfHeader(kdfArgon2id, 1, 64*1024), 255),
+		"argon2id zero":       append(kdfHeader(kdfArgon2id, 0, 64), 1),
+		"scrypt not power":    kdfHeader(kdfScrypt, 1000, 8, 1),
+		"unknown id":          {0x7f},
+		"truncated":           {kdfArgon2id, 0, 0},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := parseKdfHeader(header); err == nil {
+				t.Error("header is accepted")
+			}
+		})
+	}
+}
+
+func TestExpensiveKdfIsRejectedBeforeDerivation(t *testing.T) {
+	encryptor := NewEncryptor("key", "salt", "AES-256-CBC")
+	// 4 TiB of argon2id memory, the test runs out of memory if it is derived
+	header := append(kdfHeader(kdfArgon2id, 1, 1<<32-1), 4)
+
+	method := []byte("AES-256-CBC")
+	body := append(append([]byte{byte(len(method))}, method...), header...)
+	body = append(body, make([]byte, 2*ivLen+sha2len)...)
+	value := []byte(EnvelopeV2Prefix + base64.StdEncoding.EncodeToString(body))
+
+	if encryptor.IsEncrypted(value) {
+		t.Error("envelope is accepted")
+	}
+
+	signature := SignatureV1Prefix + base64.StdEncoding.EncodeToString(append(header, make([]byte, sha2len)...))
+	if err := encryptor.Verify([]byte("data"), signature); err == nil {
+		t.Error("signature is accepted")
+	}
+
+	stream := append(append([]byte{}, streamMagic...), header...)
+	stream = append(stream, make([]byte, streamSaltLen)...)
+	stream = binary.BigEndian.AppendUint32(stream, StreamChunkSize)
+	if _, err := encryptor.DecryptReader(bytes.NewReader(stream)); err == nil {
+		t.Error("stream is accepted")
+	}
+}
+
+func TestKdfValidateLimits(t *testing.T) {
+	for _, kdf := range []Kdf{
+		{Algorithm: KdfPbkdf2},
+		{Algorithm: KdfScrypt},
+		{Algorithm: KdfArgon2id},
+		{Algorithm: KdfArgon2id, Memory: 1 << 20, Iterations: maxArgon2idIterations},
+		{Algorithm: KdfScrypt, N: maxScryptN, R: 8, P: maxScryptP},
+	} {
+		if err := kdf.Validate(); err != nil {
+			t.Errorf("%+v is rejected: %v", kdf, err)
+		}
+	}
+
+	for _, kdf := range []Kdf{
+		{Algorithm: KdfArgon2id, Memory: 1<<20 + 1},
+		{Algorithm: KdfScrypt, N: 1 << 21},
+		{Algorithm: "bcrypt"},
+	} {
+		if err := kdf.Validate(); err == nil {
+			t.Errorf("%+v is accepted", kdf)
+		}
+	}
+}
+
+// kdfHeader returns kdf id with big endian uint32 parameters
+func kdfHeader(id byte, parameters ...uint32) []byte {
+	header := []byte{id}
+	for _, parameter := range parameters {
+		header = binary.BigEndian.AppendUint32(header, parameter)
+	}
+
+	return header
+}
@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// signature v1: "v1:" + base64(kdf header | HMAC-SHA256 of the data)
+// the mac key is derived (HKDF) from the master key, so it is independent of the encryption keys
+const SignatureV1Prefix = "v1:"
+
+var signatureKeyInfo = []byte("asterizm signature key v1")
+
+// ErrSignatureMismatch is returned if the data doesn't match the signature
+var ErrSignatureMismatch = errors.New("signature mismatch")
+
+// Sign returns signature of the data with the encryptor key and salt
+func (e *Encryptor) Sign(data []byte) (string, error) {
+	if err := e.kdf.Validate(); err != nil {
+		return "", err
+	}
+
+	mac, err := e.signatureMac(e.kdf, data)
+	if err != nil {
+		return "", err
+	}
+
+	return SignatureV1Prefix + base64.StdEncoding.EncodeToString(append(e.kdf.header(), mac...)), nil
+}
+
+// Verify checks the signature of the data, the key derivation is taken from the signature
+func (e *Encryptor) Verify(data []byte, signature string) error {
+	encoded, ok := strings.CutPrefix(strings.TrimSpace(signature), SignatureV1Prefix)
+	if !ok {
+		return errors.New("unsupported signature format")
+	}
+
+	body, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return err
+	}
+
+	kdf, kdfLen, err := parseKdfHeader(body)
+	if err != nil {
+		return err
+	}
+
+	if len(body) != kdfLen+sha2len {
+		return errors.New("bad signature lenght")
+	}
+
+	mac, err := e.signatureMac(kdf, data)
+	if err != nil {
+		return err
+	}
+
+	if !hmac.Equal(mac, body[kdfLen:]) {
+		return ErrSignatureMismatch
+	}
+
+	return nil
+}
+
+func (e *Encryptor) signatureMac(kdf Kdf, data []byte) ([]byte, error) {
+	masterKey, err := e.buildKey("", "", kdf)
+	if err != nil {
+		return nil, err
+	}
+
+	signatureKey := make([]byte, sha2len)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, masterKey, nil, signatureKeyInfo), signatureKey); err != nil {
+		return nil, err
+	}
+
+	return e.computeHmac(signatureKey, data), nil
+}
@@ -0,0 +1,259 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// stream: magic | kdf id | kdf cost parameters | file salt | chunk size | chunks
+// every chunk is sealed with AES-256-GCM, the header is additional data of every chunk
+// nonce is a chunk counter with the last chunk flag, so reordered, dropped or truncated chunks are rejected
+const (
+	StreamChunkSize = 64 * 1024
+
+	streamSaltLen  = 16
+	streamNonceLen = 12
+	streamTagLen   = 16
+	// limits memory of the decryption, chunk size is read from the stream
+	streamMaxChunkSize = 16 * 1024 * 1024
+)
+
+var (
+	streamMagic   = []byte("ASTZS1")
+	streamKeyInfo = []byte("asterizm stream key v1")
+)
+
+type streamWriter struct {
+	dst     io.Writer
+	aead    cipher.AEAD
+	header  []byte
+	buf     []byte
+	counter uint64
+	closed  bool
+}
+
+type streamReader struct {
+	src       *bufio.Reader
+	aead      cipher.AEAD
+	header    []byte
+	chunk     []byte
+	counter   uint64
+	plaintext []byte
+	done      bool
+}
+
+// EncryptWriter returns writer which encrypts data to dst in authenticated chunks
+// Close must be called to write the last chunk, dst is not closed
+func (e *Encryptor) EncryptWriter(dst io.Writer) (io.WriteCloser, error) {
+	if err := e.kdf.Validate(); err != nil {
+		return nil, err
+	}
+
+	masterKey, err := e.buildKey("", "", e.kdf)
+	if err != nil {
+		return nil, err
+	}
+
+	salt, err := GenerateRandomBytes(streamSaltLen)
+	if err != nil {
+		return nil, err
+	}
+
+	header := append(append([]byte{}, streamMagic...), e.kdf.header()...)
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, StreamChunkSize)
+
+	aead, err := streamCipher(masterKey, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := dst.Write(header); err != nil {
+		return nil, err
+	}
+
+	return &streamWriter{
+		dst:    dst,
+		aead:   aead,
+		header: header,
+		buf:    make([]byte, 0, StreamChunkSize),
+	}, nil
+}
+
+// DecryptReader returns reader of the data encrypted by EncryptWriter
+// data is returned only after its chunk authentication, the last read fails if the stream is truncated
+func (e *Encryptor) DecryptReader(src io.Reader) (io.Reader, error) {
+	reader := bufio.NewReaderSize(src, StreamChunkSize+streamTagLen)
+
+	magic := make([]byte, len(streamMagic))
+	if _, err := io.ReadFull(reader, magic); err != nil || !bytes.Equal(magic, streamMagic) {
+		return nil, errors.New("not an encrypted stream")
+	}
+
+	// kdf header length depends on its id, the longest is read by parts
+	kdfHeader, err := reader.Peek(1)
+	if err != nil {
+		return nil, errors.New("bad stream header")
+	}
+
+	if length := kdfHeaderLen(kdfHeader[0]); length > 1 {
+		kdfHeader, _ = reader.Peek(length)
+	}
+	kdf, kdfLen, err := parseKdfHeader(kdfHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	header := append(append([]byte{}, streamMagic...), kdfHeader[:kdfLen]...)
+	reader.Discard(kdfLen)
+
+	rest := make([]byte, streamSaltLen+4)
+	if _, err := io.ReadFull(reader, rest); err != nil {
+		return nil, errors.New("bad stream header")
+	}
+	header = append(header, rest...)
+
+	chunkSize := int(binary.BigEndian.Uint32(rest[streamSaltLen:]))
+	if chunkSize == 0 || chunkSize > streamMaxChunkSize {
+		return nil, fmt.Errorf("bad stream chunk size %d", chunkSize)
+	}
+
+	masterKey, err := e.buildKey("", "", kdf)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := streamCipher(masterKey, rest[:streamSaltLen])
+	if err != nil {
+		return nil, err
+	}
+
+	return &streamReader{
+		src:    bufio.NewReaderSize(reader, chunkSize+streamTagLen+1),
+		aead:   aead,
+		header: header,
+		chunk:  make([]byte, chunkSize+streamTagLen),
+	}, nil
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("write to closed stream")
+	}
+
+	written := 0
+	for len(p) > 0 {
+		// a full chunk is sealed only when more data comes, so the last chunk is known on close
+		if len(w.buf) == StreamChunkSize {
+			if err := w.seal(false); err != nil {
+				return written, err
+			}
+		}
+
+		n := copy(w.buf[len(w.buf):StreamChunkSize], p)
+		w.buf = w.buf[:len(w.buf)+n]
+		p = p[n:]
+		written += n
+	}
+
+	return written, nil
+}
+
+func (w *streamWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+
+	w.closed = true
+	return w.seal(true)
+}
+
+func (w *streamWriter) seal(last bool) error {
+	chunk := w.aead.Seal(nil, streamNonce(w.counter, last), w.buf, w.header)
+	if _, err := w.dst.Write(chunk); err != nil {
+		return err
+	}
+
+	w.counter++
+	w.buf = w.buf[:0]
+
+	return nil
+}
+
+func (r *streamReader) Read(p []byte) (int, error) {
+	for len(r.plaintext) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.plaintext)
+	r.plaintext = r.plaintext[n:]
+
+	return n, nil
+}
+
+func (r *streamReader) open() error {
+	chunk := r.chunk
+	n, err := io.ReadFull(r.src, chunk)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	// the last chunk is the one followed by the end of stream
+	last := n < len(chunk)
+	if !last {
+		if _, err := r.src.Peek(1); errors.Is(err, io.EOF) {
+			last = true
+		}
+	}
+
+	plaintext, err := r.aead.Open(chunk[:0], streamNonce(r.counter, last), chunk[:n], r.header)
+	if err != nil {
+		return errors.New("stream authentication failed, it is corrupted or truncated")
+	}
+
+	r.counter++
+	r.plaintext = plaintext
+	r.done = last
+
+	return nil
+}
+
+// streamCipher returns AES-256-GCM of the file key
+func streamCipher(masterKey, salt []byte) (cipher.AEAD, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, masterKey, salt, streamKeyInfo), key); err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCMWithNonceSize(block, streamNonceLen)
+}
+
+func streamNonce(counter uint64, last bool) []byte {
+	nonce := make([]byte, streamNonceLen)
+	binary.BigEndian.PutUint64(nonce[3:11], counter)
+	if last {
+		nonce[11] = 1
+	}
+
+	return nonce
+}
@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func encryptStream(t *testing.T, encryptor *Encryptor, data []byte, writeSize int) []byte {
+	t.Helper()
+
+	encrypted := &bytes.Buffer{}
+	writer, err := encryptor.EncryptWriter(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for part := data; len(part) > 0; {
+		n := writeSize
+		if n > len(part) {
+			n = len(part)
+		}
+		if _, err := writer.Write(part[:n]); err != nil {
+			t.Fatal(err)
+		}
+		part = part[n:]
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return encrypted.Bytes()
+}
+
+func decryptStream(encryptor *Encryptor, encrypted []byte) ([]byte, error) {
+	reader, err := encryptor.DecryptReader(bytes.NewReader(encrypted))
+	if err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(reader)
+}
+
+func TestStreamRoundTrip(t *testing.T) {
+	encryptor := NewEncryptor(testKey, testSalt, "AES-256-CBC")
+
+	for _, size := range []int{0, 1, StreamChunkSize - 1, StreamChunkSize, StreamChunkSize + 1, 3*StreamChunkSize + 17} {
+		data := make([]byte, size)
+		for i := range data {
+			data[i] = byte(i * 7)
+		}
+
+		// writes smaller and larger than a chunk
+		for _, writeSize := range []int{1000, 2*StreamChunkSize + 5} {
+			decrypted, err := decryptStream(encryptor, encryptStream(t, encryptor, data, writeSize))
+			if err != nil {
+				t.Errorf("size %d, write size %d: %v", size, writeSize, err)
+				continue
+			}
+
+			if !bytes.Equal(decrypted, data) {
+				t.Errorf("size %d, write size %d: decrypted data differs", size, writeSize)
+			}
+		}
+	}
+}
+
+func TestStreamRejectsTampering(t *testing.T) {
+	encryptor := NewEncryptor(testKey, testSalt, "AES-256-CBC")
+
+	// an empty stream is the header and the tag of the empty last chunk
+	headerLen := len(encryptStream(t, encryptor, nil, 1)) - streamTagLen
+	sealedLen := StreamChunkSize + streamTagLen
+
+	encrypted := encryptStream(t, encryptor, make([]byte, 3*StreamChunkSize+17), StreamChunkSize)
+	chunk := func(i int) []byte {
+		end := headerLen + (i+1)*sealedLen
+		if end > len(encrypted) {
+			end = len(encrypted)
+		}
+		return encrypted[headerLen+i*sealedLen : end]
+	}
+
+	flip := func(i int) []byte {
+		tampered := bytes.Clone(encrypted)
+		tampered[i] ^= 1
+		return tampered
+	}
+
+	for name, tampered := range map[string][]byte{
+		"header only":             encrypted[:headerLen],
+		"truncated at chunk 1":    encrypted[:headerLen+sealedLen],
+		"truncated at chunk 3":    encrypted[:headerLen+3*sealedLen],
+		"truncated in chunk":      encrypted[:headerLen+sealedLen+100],
+		"last chunk dropped":      encrypted[:len(encrypted)-len(chunk(3))],
+		"chunks 0 and 1 swapped":  bytes.Join([][]byte{encrypted[:headerLen], chunk(1), chunk(0), chunk(2), chunk(3)}, nil),
+		"chunk 1 repeated":        bytes.Join([][]byte{encrypted[:headerLen], chunk(0), chunk(1), chunk(1), chunk(2), chunk(3)}, nil),
+		"flipped salt":            flip(headerLen - 5),
+		"flipped chunk size":      flip(headerLen - 1),
+		"flipped first byte":      flip(headerLen),
+		"flipped second chunk":    flip(headerLen + sealedLen + 10),
+		"flipped tag":             flip(headerLen + sealedLen - 1),
+		"flipped last byte":       flip(len(encrypted) - 1),
+		"appended byte":           append(bytes.Clone(encrypted), 0),
+		"another encryption key":  encryptStream(t, NewEncryptor("another-key", testSalt, "AES-256-CBC"), make([]byte, 10), 10),
+		"not an encrypted stream": []byte("plain text"),
+	} {
+		if _, err := decryptStream(encryptor, tampered); err == nil {
+			t.Errorf("%s: stream is accepted", name)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+// generates legacyVectors of encryption_test.go with node crypto: node utils/testdata/legacy_vectors.js
+// the values follow the client-server format, the nonce and the iv are fixed to keep the output stable
+const crypto = require('crypto');
+
+const key = 'test-encryption-key';
+const salt = 'test-encryption-salt';
+const plaintext = Buffer.from('0x4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318');
+const hashKey = crypto.createHash('sha256').update(salt + key + salt).digest();
+
+// nonce | ciphertext | tag
+for (const size of [16, 32]) {
+  const nonce = Buffer.from('000102030405060708090a0b', 'hex');
+  const cipher = crypto.createCipheriv(`aes-${size * 8}-gcm`, hashKey.subarray(0, size), nonce);
+  const ciphertext = Buffer.concat([cipher.update(plaintext), cipher.final()]);
+  console.log(`AES-${size * 8}-GCM`, Buffer.concat([nonce, ciphertext, cipher.getAuthTag()]).toString('base64'));
+}
+
+// iv | hmac | ciphertext
+const iv = Buffer.from('0f0e0d0c0b0a09080706050403020100', 'hex');
+const cipher = crypto.createCipheriv('aes-256-cbc', hashKey, iv);
+const ciphertext = Buffer.concat([cipher.update(plaintext), cipher.final()]);
+const hmac = crypto.createHmac('sha256', hashKey).update(ciphertext).digest();
+console.log('AES-256-CBC', Buffer.concat([iv, hmac, ciphertext]).toString('base64'));
@@ -5,6 +5,11 @@ import (
 	"fmt"
 	"io"
 	"math/big"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"syscall"
 	"time"
 )
 
@@ -34,6 +39,13 @@ func MapKeys[T comparable, R any](object map[T]R) []T {
 	return keys
 }
 
+func SortedKeys[R any](object map[string]R) []string {
+	keys := MapKeys(object)
+	sort.Strings(keys)
+
+	return keys
+}
+
 func GenerateRandomBytes(size int) ([]byte, error) {
 	bytes := make([]byte, size)
 	if _, err := io.ReadFull(rand.Reader, bytes); err != nil {
@@ -103,3 +115,97 @@ func CheckPkcs7PaddingIsValid(input []byte, paddingLength int) error {
 	}
 	return nil
 }
+
+// Levenshtein returns edit distance between two strings
+func Levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = prev[j] + 1
+			if curr[j-1]+1 < curr[j] {
+				curr[j] = curr[j-1] + 1
+			}
+			if prev[j-1]+cost < curr[j] {
+				curr[j] = prev[j-1] + cost
+			}
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
+
+// ClosestString returns the most similar candidate or empty string if nothing is close enough
+func ClosestString(str string, candidates []string) string {
+	closest := ""
+	bestDistance := len(str)/2 + 1
+
+	for _, candidate := range candidates {
+		if strings.EqualFold(str, candidate) {
+			return candidate
+		}
+
+		distance := Levenshtein(strings.ToLower(str), strings.ToLower(candidate))
+		if distance < bestDistance {
+			closest = candidate
+			bestDistance = distance
+		}
+	}
+
+	return closest
+}
+
+// WriteFileAtomic replaces the file with a renamed temporary file
+// mode and owner of the existing file are preserved
+func WriteFileAtomic(name string, data []byte, perm os.FileMode) error {
+	var stat *syscall.Stat_t
+	if info, err := os.Stat(name); err == nil {
+		perm = info.Mode().Perm()
+		stat, _ = info.Sys().(*syscall.Stat_t)
+	}
+
+	file, err := os.CreateTemp(path.Dir(name), "."+path.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(file.Name(), perm); err != nil {
+		return err
+	}
+
+	if stat != nil {
+		// keeps files created by sudo accessible to their owner
+		if err := os.Chown(file.Name(), int(stat.Uid), int(stat.Gid)); err != nil {
+			return err
+		}
+	}
+
+	return os.Rename(file.Name(), name)
+}
@@ -0,0 +1,42 @@
+package utils
+
+import "testing"
+
+func TestLevenshtein(t *testing.T) {
+	for _, test := range []struct {
+		a, b     string
+		distance int
+	}{
+		{a: "", b: "", distance: 0},
+		{a: "", b: "abc", distance: 3},
+		{a: "kitten", b: "sitting", distance: 3},
+		{a: "MaxResendTry", b: "MaxResendTries", distance: 3},
+		{a: "ETHH", b: "ETH", distance: 1},
+		{a: "сеть", b: "сети", distance: 1},
+	} {
+		if distance := Levenshtein(test.a, test.b); distance != test.distance {
+			t.Errorf("Levenshtein(%q, %q) = %d, want %d", test.a, test.b, distance, test.distance)
+		}
+
+		if distance := Levenshtein(test.b, test.a); distance != test.distance {
+			t.Errorf("Levenshtein(%q, %q) = %d, want %d", test.b, test.a, distance, test.distance)
+		}
+	}
+}
+
+func TestClosestString(t *testing.T) {
+	candidates := []string{"MaxOutOfGasResendTries", "MaxResendTries", "OwnerPrivateKey", "RPC"}
+
+	for str, expected := range map[string]string{
+		"MaxResendTry":    "MaxResendTries",
+		"maxresendtries":  "MaxResendTries",
+		"OwnerPrivateKye": "OwnerPrivateKey",
+		"rpc":             "RPC",
+		// too far from every candidate
+		"Timeout": "",
+	} {
+		if closest := ClosestString(str, candidates); closest != expected {
+			t.Errorf("ClosestString(%q) = %q, want %q", str, closest, expected)
+		}
+	}
+}